@@ -0,0 +1,94 @@
+package analytics
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+// DefaultPrecision is the default HyperLogLog precision: 2^14 registers (16KB), ~0.81% standard error
+const DefaultPrecision = 14
+
+// NewHyperLogLog creates a HyperLogLog sketch counting the distinct values using 2^precision registers (one byte each).
+// The standard error of the estimate is 1.04/sqrt(2^precision), i.e. 0.81% for the default precision of 14,
+// so ~99.7% of the estimates are within 3 standard errors (2.4%) of the actual number of distinct values.
+// precision is clamped between 4 and 18
+func NewHyperLogLog(precision uint8) *HyperLogLog {
+	if precision < 4 {
+		precision = 4
+	}
+	if precision > 18 {
+		precision = 18
+	}
+	return &HyperLogLog{
+		precision: precision,
+		registers: make([]uint8, 1<<precision),
+	}
+}
+
+// HyperLogLog represents a HyperLogLog sketch, estimating the number of distinct values using constant memory
+type HyperLogLog struct {
+	precision uint8
+	registers []uint8
+}
+
+// Add adds a value to the sketch
+func (h *HyperLogLog) Add(value string) {
+	hash := hash64(value)
+	index := hash >> (64 - h.precision)
+	// the guard bit bounds the rank when all the remaining bits are zero
+	rank := uint8(bits.LeadingZeros64(hash<<h.precision|1<<(h.precision-1))) + 1
+	if rank > h.registers[index] {
+		h.registers[index] = rank
+	}
+}
+
+// Count returns the estimated number of distinct values added to the sketch
+func (h *HyperLogLog) Count() int64 {
+	m := float64(len(h.registers))
+	sum := 0.0
+	zeros := 0
+	for _, register := range h.registers {
+		sum += math.Ldexp(1, -int(register))
+		if register == 0 {
+			zeros++
+		}
+	}
+
+	alpha := 0.7213 / (1 + 1.079/m)
+	estimate := alpha * m * m / sum
+	// small cardinalities are better estimated by linear counting
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return int64(estimate + 0.5)
+}
+
+// Merge merges another sketch into the current one, both have to use the same precision
+func (h *HyperLogLog) Merge(other *HyperLogLog) error {
+	if h.precision != other.precision {
+		return fmt.Errorf("hyperloglog precision %d and %d: %w", h.precision, other.precision, errIncompatibleStats)
+	}
+
+	for i, register := range other.registers {
+		if register > h.registers[i] {
+			h.registers[i] = register
+		}
+	}
+	return nil
+}
+
+// hash64 hashes a value using FNV-1a, followed by the murmur3 finalizer
+// to spread the bits evenly, which FNV alone does poorly for short similar values
+func hash64(value string) uint64 {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(value))
+	h := hash.Sum64()
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
@@ -0,0 +1,62 @@
+package analytics
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type hyperLogLogSuite struct {
+	suite.Suite
+}
+
+func (s *hyperLogLogSuite) Test_Count() {
+	tests := []struct {
+		name     string
+		distinct int
+	}{
+		{name: "Empty", distinct: 0},
+		{name: "Small", distinct: 100},
+		{name: "Medium", distinct: 10000},
+		{name: "Large", distinct: 500000},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			hll := NewHyperLogLog(DefaultPrecision)
+			// every value is added twice, duplicates must not be counted
+			for i := 0; i < 2*test.distinct; i++ {
+				hll.Add(fmt.Sprintf("10.0.%d.%d", i%test.distinct/256, i%test.distinct%256))
+			}
+
+			// 3 standard errors of 1.04/sqrt(2^14)
+			s.InDelta(float64(test.distinct), float64(hll.Count()), math.Ceil(0.025*float64(test.distinct)))
+		})
+	}
+}
+
+func (s *hyperLogLogSuite) Test_Merge_Success() {
+	first, second := NewHyperLogLog(DefaultPrecision), NewHyperLogLog(DefaultPrecision)
+	for i := 0; i < 60000; i++ {
+		first.Add(fmt.Sprintf("/api/users/%d", i))
+	}
+	for i := 40000; i < 100000; i++ {
+		second.Add(fmt.Sprintf("/api/users/%d", i))
+	}
+
+	err := first.Merge(second)
+
+	s.NoError(err)
+	s.InDelta(100000, float64(first.Count()), 2500)
+}
+
+func (s *hyperLogLogSuite) Test_Merge_Error() {
+	err := NewHyperLogLog(14).Merge(NewHyperLogLog(12))
+
+	s.EqualError(err, "hyperloglog precision 14 and 12: incompatible stats")
+}
+
+func TestHyperLogLog(t *testing.T) {
+	suite.Run(t, new(hyperLogLogSuite))
+}
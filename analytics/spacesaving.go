@@ -0,0 +1,149 @@
+package analytics
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// DefaultCapacity is the default number of values tracked by the Space-Saving sketch
+const DefaultCapacity = 1000
+
+// NewSpaceSaving creates a Space-Saving sketch finding the most frequent values (heavy hitters)
+// while tracking at most capacity values. Given N values were added:
+// every value occurring more than N/capacity times is guaranteed to be tracked
+// and the count of every tracked value overestimates its actual count by at most N/capacity
+func NewSpaceSaving(capacity int) *SpaceSaving {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	return &SpaceSaving{
+		capacity: capacity,
+		counters: &ssHeap{indexes: map[string]int{}},
+	}
+}
+
+// SpaceSaving represents a Space-Saving sketch, counting the most frequent values using constant memory
+type SpaceSaving struct {
+	capacity int
+	// counters is a min heap of the tracked values by their count,
+	// the least frequent value is replaced whenever a new value has to be tracked
+	counters *ssHeap
+}
+
+// Add adds a value to the sketch
+func (s *SpaceSaving) Add(value string) {
+	h := s.counters
+	if i, ok := h.indexes[value]; ok {
+		h.items[i].count++
+		heap.Fix(h, i)
+		return
+	}
+	if h.Len() < s.capacity {
+		heap.Push(h, &ssCounter{value: value, count: 1})
+		return
+	}
+
+	// the new value takes over the least frequent value, inheriting its count as the possible error
+	least := h.items[0]
+	delete(h.indexes, least.value)
+	least.value = value
+	least.count++
+	h.indexes[value] = 0
+	heap.Fix(h, 0)
+}
+
+// Top returns the N most frequent values ordered by their estimated count
+func (s *SpaceSaving) Top(n int) []Count {
+	counts := make([]Count, 0, s.counters.Len())
+	for _, c := range s.counters.items {
+		counts = append(counts, Count{Value: c.value, Count: c.count})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count == counts[j].Count {
+			return counts[i].Value < counts[j].Value
+		}
+		return counts[i].Count > counts[j].Count
+	})
+	if n > 0 && len(counts) > n {
+		counts = counts[:n]
+	}
+	return counts
+}
+
+// Merge merges another sketch into the current one.
+// Values tracked by only one of the sketches are counted with the smallest count of the other (full) sketch,
+// so the counts remain overestimates within the sum of both error bounds
+func (s *SpaceSaving) Merge(other *SpaceSaving) {
+	counts := map[string]int64{}
+	for _, c := range s.counters.items {
+		counts[c.value] += c.count
+	}
+	for _, c := range other.counters.items {
+		counts[c.value] += c.count
+	}
+	for value := range counts {
+		if _, ok := s.counters.indexes[value]; !ok {
+			counts[value] += s.min()
+		}
+		if _, ok := other.counters.indexes[value]; !ok {
+			counts[value] += other.min()
+		}
+	}
+
+	h := &ssHeap{indexes: map[string]int{}}
+	for value, count := range counts {
+		if h.Len() < s.capacity {
+			heap.Push(h, &ssCounter{value: value, count: count})
+			continue
+		}
+		if count > h.items[0].count {
+			delete(h.indexes, h.items[0].value)
+			h.items[0] = &ssCounter{value: value, count: count}
+			h.indexes[value] = 0
+			heap.Fix(h, 0)
+		}
+	}
+	s.counters = h
+}
+
+// min returns the smallest count of a full sketch, the most a value missing from the sketch could have occurred
+func (s *SpaceSaving) min() int64 {
+	if s.counters.Len() < s.capacity {
+		return 0
+	}
+	return s.counters.items[0].count
+}
+
+type ssCounter struct {
+	value string
+	count int64
+}
+
+// ssHeap is a min heap of counters keeping track of the index of every value inside the heap
+type ssHeap struct {
+	items   []*ssCounter
+	indexes map[string]int
+}
+
+func (h *ssHeap) Len() int { return len(h.items) }
+
+func (h *ssHeap) Less(i, j int) bool { return h.items[i].count < h.items[j].count }
+
+func (h *ssHeap) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+	h.indexes[h.items[i].value] = i
+	h.indexes[h.items[j].value] = j
+}
+
+func (h *ssHeap) Push(x interface{}) {
+	c := x.(*ssCounter)
+	h.indexes[c.value] = len(h.items)
+	h.items = append(h.items, c)
+}
+
+func (h *ssHeap) Pop() interface{} {
+	c := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	delete(h.indexes, c.value)
+	return c
+}
@@ -0,0 +1,72 @@
+package analytics
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type spaceSavingSuite struct {
+	suite.Suite
+}
+
+func (s *spaceSavingSuite) Test_Top_Exact() {
+	ss := NewSpaceSaving(10)
+	for _, value := range []string{"/a", "/b", "/a", "/c", "/a", "/b"} {
+		ss.Add(value)
+	}
+
+	s.Equal([]Count{{Value: "/a", Count: 3}, {Value: "/b", Count: 2}}, ss.Top(2))
+	s.Len(ss.Top(0), 3)
+}
+
+func (s *spaceSavingSuite) Test_Top_HeavyHitters() {
+	const capacity = 100
+	ss := NewSpaceSaving(capacity)
+	// 5 heavy hitters hidden among 20000 values occurring once
+	total := 0
+	for i := 0; i < 20000; i++ {
+		ss.Add(fmt.Sprintf("/rare/%d", i))
+		total++
+		if i%10 == 0 {
+			ss.Add(fmt.Sprintf("/hot/%d", i/10%5))
+			total++
+		}
+	}
+
+	top := ss.Top(5)
+
+	s.Len(top, 5)
+	for _, c := range top {
+		s.Contains(c.Value, "/hot/")
+		// every count overestimates the actual count (400) by at most N/capacity
+		s.GreaterOrEqual(c.Count, int64(400))
+		s.LessOrEqual(c.Count, int64(400+total/capacity))
+	}
+}
+
+func (s *spaceSavingSuite) Test_Merge() {
+	first, second := NewSpaceSaving(50), NewSpaceSaving(50)
+	for i := 0; i < 5000; i++ {
+		first.Add(fmt.Sprintf("/first/%d", i))
+		second.Add(fmt.Sprintf("/second/%d", i))
+		if i%5 == 0 {
+			first.Add("/hot")
+			second.Add("/hot")
+		}
+	}
+
+	first.Merge(second)
+
+	top := first.Top(1)
+	s.Require().Len(top, 1)
+	s.Equal("/hot", top[0].Value)
+	s.GreaterOrEqual(top[0].Count, int64(2000))
+	s.LessOrEqual(top[0].Count, int64(2000+12000/50))
+	s.Len(first.Top(0), 50)
+}
+
+func TestSpaceSaving(t *testing.T) {
+	suite.Run(t, new(spaceSavingSuite))
+}
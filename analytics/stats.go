@@ -0,0 +1,461 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/steevehook/weblog-analytics/logging"
+)
+
+var (
+	errIncompatibleStats = errors.New("incompatible stats")
+	errTooManyIntervals  = errors.New("too many intervals")
+)
+
+const (
+	// DefaultInterval is the default interval the requests are counted per
+	DefaultInterval = time.Minute
+	// MaxIntervals is the maximum number of intervals a report counts the requests per,
+	// the interval of a report spanning more intervals is widened, see Stats.Report
+	MaxIntervals = 10000
+)
+
+// CheckInterval checks that the time window of the reader configuration is split into at most MaxIntervals intervals.
+// Only the windows of a known length are checked (from and to, or since), the reports of the windows
+// ending at the current time widen their interval instead
+func CheckInterval(cfg logging.ReaderConfig, interval time.Duration) error {
+	var window time.Duration
+	switch {
+	case !cfg.From.IsZero() && !cfg.To.IsZero():
+		window = cfg.To.Sub(cfg.From)
+	case cfg.From.IsZero() && cfg.To.IsZero():
+		window = cfg.Since
+	}
+	if interval <= 0 || window <= 0 {
+		return nil
+	}
+
+	if int64(window/interval)+1 > MaxIntervals {
+		return fmt.Errorf("interval %v over %v: more than %d intervals: %w", interval, window, MaxIntervals, errTooManyIntervals)
+	}
+	return nil
+}
+
+// NewStats creates a new stats aggregator counting the requests per the given interval.
+// All the paths, client IPs, response sizes and durations are kept in memory to compute exact stats
+func NewStats(interval time.Duration) *Stats {
+	return newStats(interval, false, exactCounter{}, exactCounter{}, &exactQuantiles{}, &exactQuantiles{})
+}
+
+// NewApproxStats creates a new stats aggregator counting the requests per the given interval using constant memory:
+// the unique paths and client IPs are estimated by HyperLogLog sketches (~0.81% standard error),
+// the top paths and client IPs by Space-Saving sketches tracking capacity values
+// (counts overestimated by at most requests/capacity) and the quantiles by t-digest sketches, see their error bounds
+func NewApproxStats(interval time.Duration, capacity int) *Stats {
+	paths := &approxCounter{heavyHitters: NewSpaceSaving(capacity), unique: NewHyperLogLog(DefaultPrecision)}
+	ips := &approxCounter{heavyHitters: NewSpaceSaving(capacity), unique: NewHyperLogLog(DefaultPrecision)}
+	sizes := &approxQuantiles{NewTDigest(DefaultCompression)}
+	durations := &approxQuantiles{NewTDigest(DefaultCompression)}
+	return newStats(interval, true, paths, ips, sizes, durations)
+}
+
+func newStats(interval time.Duration, approximate bool, paths, ips counter, sizes, durations quantiles) *Stats {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Stats{
+		interval:    interval,
+		approximate: approximate,
+		paths:       paths,
+		ips:         ips,
+		statuses:    map[int]int64{},
+		intervals:   map[int64]int64{},
+		sizes:       sizes,
+		durations:   durations,
+	}
+}
+
+// Stats aggregates the parsed logs in a single streaming pass:
+// the number of requests and bytes served, requests per path, client IP, status code and interval,
+// the number of unique paths and client IPs and the quantiles of the response sizes and durations.
+// Stats computed over different parts of the logs can be merged together
+type Stats struct {
+	interval    time.Duration
+	approximate bool
+	requests    int64
+	bytes       int64
+	first       time.Time
+	last        time.Time
+	paths       counter
+	ips         counter
+	statuses    map[int]int64
+	intervals   map[int64]int64
+	sizes       quantiles
+	durations   quantiles
+}
+
+// Add aggregates a log entry
+func (s *Stats) Add(entry logging.Entry) {
+	s.requests++
+	s.bytes += entry.Bytes
+	if s.first.IsZero() || entry.Time.Before(s.first) {
+		s.first = entry.Time
+	}
+	if entry.Time.After(s.last) {
+		s.last = entry.Time
+	}
+	s.paths.add(entry.Path)
+	s.ips.add(entry.RemoteHost)
+	s.statuses[entry.Status]++
+	s.intervals[entry.Time.Truncate(s.interval).Unix()]++
+	s.sizes.add(float64(entry.Bytes))
+	// logs without a duration, i.e. the common log format, are left out of the duration quantiles
+	if entry.Duration > 0 {
+		s.durations.add(float64(entry.Duration) / float64(time.Millisecond))
+	}
+}
+
+// Merge merges the stats of another aggregator into the current one,
+// both have to use the same interval and be either exact or approximate
+func (s *Stats) Merge(other *Stats) error {
+	if s.interval != other.interval {
+		return fmt.Errorf("interval %v and %v: %w", s.interval, other.interval, errIncompatibleStats)
+	}
+	if s.approximate != other.approximate {
+		return fmt.Errorf("exact and approximate stats: %w", errIncompatibleStats)
+	}
+
+	s.requests += other.requests
+	s.bytes += other.bytes
+	if !other.first.IsZero() && (s.first.IsZero() || other.first.Before(s.first)) {
+		s.first = other.first
+	}
+	if other.last.After(s.last) {
+		s.last = other.last
+	}
+	err := s.paths.merge(other.paths)
+	if err != nil {
+		return err
+	}
+	err = s.ips.merge(other.ips)
+	if err != nil {
+		return err
+	}
+	s.sizes.merge(other.sizes)
+	s.durations.merge(other.durations)
+	for status, count := range other.statuses {
+		s.statuses[status] += count
+	}
+	for interval, count := range other.intervals {
+		s.intervals[interval] += count
+	}
+	return nil
+}
+
+// Aggregate aggregates the logs read by the reader, in parallel when the reader has multiple workers:
+// each worker adds its logs to its own partial stats created by newStats, which are merged once the reading is done
+func Aggregate(ctx context.Context, reader *logging.Reader, newStats func() *Stats) (*Stats, error) {
+	partials := make([]*Stats, reader.Workers())
+	for i := range partials {
+		partials[i] = newStats()
+	}
+	err := reader.AggregateEntries(ctx, func(worker int, entry logging.Entry) error {
+		partials[worker].Add(entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, partial := range partials[1:] {
+		err := partials[0].Merge(partial)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return partials[0], nil
+}
+
+// Report creates the report of the aggregated logs keeping only the top N paths and client IPs
+func (s *Stats) Report(top int) Report {
+	report := Report{
+		Approximate:       s.approximate,
+		From:              s.first,
+		To:                s.last,
+		Requests:          s.requests,
+		Bytes:             s.bytes,
+		UniquePaths:       s.paths.distinct(),
+		UniqueIPs:         s.ips.distinct(),
+		TopPaths:          s.paths.top(top),
+		TopIPs:            s.ips.top(top),
+		BytesQuantiles:    newQuantiles(s.sizes),
+		DurationQuantiles: newQuantiles(s.durations),
+		Interval:          s.interval.String(),
+	}
+
+	for status, count := range s.statuses {
+		report.Statuses = append(report.Statuses, StatusCount{Status: status, Count: count})
+	}
+	sort.Slice(report.Statuses, func(i, j int) bool {
+		return report.Statuses[i].Status < report.Statuses[j].Status
+	})
+
+	// intervals without any request are reported as well, so the report has no gaps
+	if s.requests > 0 {
+		interval, counts := s.perInterval()
+		report.Interval = interval.String()
+		for t := s.first.Truncate(interval); !t.After(s.last); t = t.Add(interval) {
+			report.PerInterval = append(report.PerInterval, IntervalCount{
+				Time:  t,
+				Count: counts[t.Unix()],
+			})
+		}
+	}
+	return report
+}
+
+// perInterval returns the interval the requests are reported per, alongside the number of requests per interval.
+// The interval is widened to a multiple of the configured one when the logs span more than MaxIntervals intervals
+func (s *Stats) perInterval() (time.Duration, map[int64]int64) {
+	n := int64(s.last.Sub(s.first.Truncate(s.interval))/s.interval) + 1
+	if n <= MaxIntervals {
+		return s.interval, s.intervals
+	}
+
+	// the first interval may begin up to a whole interval before the first log, hence MaxIntervals-1
+	interval := s.interval * time.Duration((n+MaxIntervals-2)/(MaxIntervals-1))
+	counts := make(map[int64]int64, len(s.intervals))
+	for t, count := range s.intervals {
+		counts[time.Unix(t, 0).Truncate(interval).Unix()] += count
+	}
+	return interval, counts
+}
+
+// Report represents the aggregated stats of the logs.
+// Approximate reports estimate the unique and top paths and client IPs as well as the quantiles
+type Report struct {
+	Approximate bool      `json:"approximate"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	Requests    int64     `json:"requests"`
+	Bytes       int64     `json:"bytes"`
+	UniquePaths int64     `json:"unique_paths"`
+	UniqueIPs   int64     `json:"unique_ips"`
+	TopPaths    []Count   `json:"top_paths"`
+	TopIPs      []Count   `json:"top_ips"`
+	// BytesQuantiles are the quantiles of the response sizes
+	BytesQuantiles Quantiles `json:"bytes_quantiles"`
+	// DurationQuantiles are the quantiles of the request durations in milliseconds
+	DurationQuantiles Quantiles       `json:"duration_ms_quantiles"`
+	Statuses          []StatusCount   `json:"statuses"`
+	Interval          string          `json:"interval"`
+	PerInterval       []IntervalCount `json:"requests_per_interval"`
+}
+
+// Quantiles represents the median, 90th and 99th percentiles of a distribution
+type Quantiles struct {
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P99 float64 `json:"p99"`
+}
+
+func newQuantiles(q quantiles) Quantiles {
+	return Quantiles{
+		P50: q.quantile(0.5),
+		P90: q.quantile(0.9),
+		P99: q.quantile(0.99),
+	}
+}
+
+// Count represents the number of requests of a value, i.e. a path or a client IP
+type Count struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// StatusCount represents the number of requests of a status code
+type StatusCount struct {
+	Status int   `json:"status"`
+	Count  int64 `json:"count"`
+}
+
+// IntervalCount represents the number of requests during the interval starting at the given time
+type IntervalCount struct {
+	Time  time.Time `json:"time"`
+	Count int64     `json:"count"`
+}
+
+// WriteTable writes the report as human readable tables
+func (report Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	mode := "exact"
+	if report.Approximate {
+		mode = "approximate"
+	}
+	fmt.Fprintf(tw, "Stats\t%s\n", mode)
+	fmt.Fprintf(tw, "From\t%s\n", report.From.Format(time.RFC3339))
+	fmt.Fprintf(tw, "To\t%s\n", report.To.Format(time.RFC3339))
+	fmt.Fprintf(tw, "Requests\t%d\n", report.Requests)
+	fmt.Fprintf(tw, "Bytes\t%d\n", report.Bytes)
+	fmt.Fprintf(tw, "Unique paths\t%d\n", report.UniquePaths)
+	fmt.Fprintf(tw, "Unique IPs\t%d\n", report.UniqueIPs)
+	q := report.BytesQuantiles
+	fmt.Fprintf(tw, "Bytes p50/p90/p99\t%.0f/%.0f/%.0f\n", q.P50, q.P90, q.P99)
+	q = report.DurationQuantiles
+	fmt.Fprintf(tw, "Duration p50/p90/p99\t%.2fms/%.2fms/%.2fms\n", q.P50, q.P90, q.P99)
+
+	fmt.Fprintf(tw, "\nPATH\tREQUESTS\n")
+	for _, c := range report.TopPaths {
+		fmt.Fprintf(tw, "%s\t%d\n", c.Value, c.Count)
+	}
+	fmt.Fprintf(tw, "\nIP\tREQUESTS\n")
+	for _, c := range report.TopIPs {
+		fmt.Fprintf(tw, "%s\t%d\n", c.Value, c.Count)
+	}
+	fmt.Fprintf(tw, "\nSTATUS\tREQUESTS\n")
+	for _, c := range report.Statuses {
+		fmt.Fprintf(tw, "%d\t%d\n", c.Status, c.Count)
+	}
+	fmt.Fprintf(tw, "\nTIME (%s)\tREQUESTS\n", report.Interval)
+	for _, c := range report.PerInterval {
+		fmt.Fprintf(tw, "%s\t%d\n", c.Time.Format(time.RFC3339), c.Count)
+	}
+	return tw.Flush()
+}
+
+// counter counts the requests per value, i.e. per path or client IP
+type counter interface {
+	add(value string)
+	top(n int) []Count
+	distinct() int64
+	merge(other counter) error
+}
+
+// quantiles estimates the quantiles of a distribution, i.e. of the response sizes
+type quantiles interface {
+	add(value float64)
+	quantile(q float64) float64
+	merge(other quantiles)
+}
+
+// exactCounter counts the requests of every single value
+type exactCounter map[string]int64
+
+func (c exactCounter) add(value string) {
+	c[value]++
+}
+
+func (c exactCounter) top(n int) []Count {
+	return topCounts(c, n)
+}
+
+func (c exactCounter) distinct() int64 {
+	return int64(len(c))
+}
+
+func (c exactCounter) merge(other counter) error {
+	for value, count := range other.(exactCounter) {
+		c[value] += count
+	}
+	return nil
+}
+
+// approxCounter estimates the top values and the number of distinct values using sketches
+type approxCounter struct {
+	heavyHitters *SpaceSaving
+	unique       *HyperLogLog
+}
+
+func (c *approxCounter) add(value string) {
+	c.heavyHitters.Add(value)
+	c.unique.Add(value)
+}
+
+func (c *approxCounter) top(n int) []Count {
+	return c.heavyHitters.Top(n)
+}
+
+func (c *approxCounter) distinct() int64 {
+	return c.unique.Count()
+}
+
+func (c *approxCounter) merge(other counter) error {
+	o := other.(*approxCounter)
+	c.heavyHitters.Merge(o.heavyHitters)
+	return c.unique.Merge(o.unique)
+}
+
+// exactQuantiles keeps all the values to compute the exact quantiles
+type exactQuantiles struct {
+	values []float64
+	sorted bool
+}
+
+func (q *exactQuantiles) add(value float64) {
+	q.values = append(q.values, value)
+	q.sorted = false
+}
+
+// quantile linearly interpolates between the two values closest to the quantile
+func (q *exactQuantiles) quantile(quantile float64) float64 {
+	if len(q.values) == 0 {
+		return 0
+	}
+	if !q.sorted {
+		sort.Float64s(q.values)
+		q.sorted = true
+	}
+
+	rank := quantile * float64(len(q.values)-1)
+	i := int(rank)
+	if i+1 >= len(q.values) {
+		return q.values[len(q.values)-1]
+	}
+	return interpolate(rank, float64(i), float64(i+1), q.values[i], q.values[i+1])
+}
+
+func (q *exactQuantiles) merge(other quantiles) {
+	q.values = append(q.values, other.(*exactQuantiles).values...)
+	q.sorted = false
+}
+
+// approxQuantiles estimates the quantiles using a t-digest sketch
+type approxQuantiles struct {
+	digest *TDigest
+}
+
+func (q *approxQuantiles) add(value float64) {
+	q.digest.Add(value)
+}
+
+func (q *approxQuantiles) quantile(quantile float64) float64 {
+	return q.digest.Quantile(quantile)
+}
+
+func (q *approxQuantiles) merge(other quantiles) {
+	q.digest.Merge(other.(*approxQuantiles).digest)
+}
+
+// topCounts returns the N values with the most requests, ordered by their number of requests.
+// Values with the same number of requests are ordered alphabetically
+func topCounts(counts map[string]int64, top int) []Count {
+	result := make([]Count, 0, len(counts))
+	for value, count := range counts {
+		result = append(result, Count{Value: value, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count == result[j].Count {
+			return result[i].Value < result[j].Value
+		}
+		return result[i].Count > result[j].Count
+	})
+	if top > 0 && len(result) > top {
+		result = result[:top]
+	}
+	return result
+}
@@ -0,0 +1,295 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/steevehook/weblog-analytics/logging"
+)
+
+type statsSuite struct {
+	suite.Suite
+	start   time.Time
+	entries []logging.Entry
+}
+
+func (s *statsSuite) SetupSuite() {
+	s.start = time.Date(2022, time.March, 3, 2, 40, 0, 0, time.UTC)
+	s.entries = []logging.Entry{
+		{RemoteHost: "10.0.0.1", Path: "/api/users", Status: 200, Bytes: 100, Time: s.start.Add(10 * time.Second), Duration: 10 * time.Millisecond},
+		{RemoteHost: "10.0.0.2", Path: "/api/users", Status: 200, Bytes: 200, Time: s.start.Add(20 * time.Second), Duration: 20 * time.Millisecond},
+		{RemoteHost: "10.0.0.1", Path: "/api/orders", Status: 500, Bytes: 50, Time: s.start.Add(70 * time.Second), Duration: 30 * time.Millisecond},
+		{RemoteHost: "10.0.0.1", Path: "/login", Status: 302, Bytes: 0, Time: s.start.Add(190 * time.Second)},
+		{RemoteHost: "10.0.0.3", Path: "/api/users", Status: 404, Bytes: 10, Time: s.start.Add(200 * time.Second)},
+	}
+}
+
+func (s *statsSuite) Test_Report() {
+	stats := NewStats(time.Minute)
+	for _, entry := range s.entries {
+		stats.Add(entry)
+	}
+
+	report := stats.Report(2)
+
+	s.Equal(s.expectedReport(), report)
+}
+
+func (s *statsSuite) Test_Merge_Success() {
+	first, second := NewStats(time.Minute), NewStats(time.Minute)
+	for i, entry := range s.entries {
+		if i%2 == 0 {
+			first.Add(entry)
+		} else {
+			second.Add(entry)
+		}
+	}
+
+	err := second.Merge(first)
+
+	s.NoError(err)
+	s.Equal(s.expectedReport(), second.Report(2))
+}
+
+func (s *statsSuite) Test_Report_Approximate() {
+	stats := NewApproxStats(time.Minute, 10)
+	for _, entry := range s.entries {
+		stats.Add(entry)
+	}
+
+	report := stats.Report(2)
+
+	// the sketches are exact as long as there are fewer values than their capacity
+	expected := s.expectedReport()
+	expected.Approximate = true
+	s.InDelta(expected.BytesQuantiles.P50, report.BytesQuantiles.P50, 1)
+	s.InDelta(expected.DurationQuantiles.P50, report.DurationQuantiles.P50, 1)
+	report.BytesQuantiles, expected.BytesQuantiles = Quantiles{}, Quantiles{}
+	report.DurationQuantiles, expected.DurationQuantiles = Quantiles{}, Quantiles{}
+	s.Equal(expected, report)
+}
+
+func (s *statsSuite) Test_Merge_Approximate() {
+	first, second := NewApproxStats(time.Minute, 10), NewApproxStats(time.Minute, 10)
+	for i, entry := range s.entries {
+		if i%2 == 0 {
+			first.Add(entry)
+		} else {
+			second.Add(entry)
+		}
+	}
+
+	err := second.Merge(first)
+
+	s.NoError(err)
+	report := second.Report(2)
+	s.Equal(int64(5), report.Requests)
+	s.Equal(int64(3), report.UniquePaths)
+	s.Equal(int64(3), report.UniqueIPs)
+	s.Equal([]Count{{Value: "/api/users", Count: 3}, {Value: "/api/orders", Count: 1}}, report.TopPaths)
+}
+
+func (s *statsSuite) Test_Merge_Error() {
+	tests := []struct {
+		name          string
+		stats         *Stats
+		other         *Stats
+		expectedError string
+	}{
+		{
+			name:          "Different Intervals",
+			stats:         NewStats(time.Minute),
+			other:         NewStats(time.Hour),
+			expectedError: "interval 1m0s and 1h0m0s: incompatible stats",
+		},
+		{
+			name:          "Exact And Approximate",
+			stats:         NewStats(time.Minute),
+			other:         NewApproxStats(time.Minute, 10),
+			expectedError: "exact and approximate stats: incompatible stats",
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			err := test.stats.Merge(test.other)
+
+			s.EqualError(err, test.expectedError)
+		})
+	}
+}
+
+func (s *statsSuite) Test_Aggregate() {
+	dir := s.T().TempDir()
+	var logs strings.Builder
+	for _, entry := range s.entries {
+		logs.WriteString(fmt.Sprintf(
+			"%s - - [%s] \"GET %s HTTP/1.0\" %d %d\n",
+			entry.RemoteHost, entry.Time.Format("02/Jan/2006:15:04:05 -0700"), entry.Path, entry.Status, entry.Bytes,
+		))
+	}
+	s.Require().NoError(os.WriteFile(path.Join(dir, "http.log"), []byte(logs.String()), 0644))
+	reader, err := logging.NewReader(logging.ReaderConfig{
+		Directory: dir,
+		From:      s.start,
+		Workers:   4,
+	})
+	s.Require().NoError(err)
+
+	stats, err := Aggregate(context.Background(), reader, func() *Stats {
+		return NewStats(time.Minute)
+	})
+
+	s.Require().NoError(err)
+	expected := s.expectedReport()
+	// the apache common log format has no durations
+	expected.DurationQuantiles = Quantiles{}
+	// the parsed times have a fixed zone instead of UTC, so the reports are compared as JSON
+	expectedJSON, err := json.Marshal(expected)
+	s.Require().NoError(err)
+	actualJSON, err := json.Marshal(stats.Report(2))
+	s.Require().NoError(err)
+	s.JSONEq(string(expectedJSON), string(actualJSON))
+}
+
+func (s *statsSuite) Test_Report_Empty() {
+	report := NewStats(0).Report(10)
+
+	s.Equal(int64(0), report.Requests)
+	s.Equal("1m0s", report.Interval)
+	s.Empty(report.PerInterval)
+}
+
+func (s *statsSuite) Test_Report_WidenedInterval() {
+	stats := NewStats(time.Second)
+	// the logs span 18001 intervals of a second
+	stats.Add(logging.Entry{Path: "/a", Time: s.start})
+	stats.Add(logging.Entry{Path: "/b", Time: s.start.Add(5 * time.Hour)})
+
+	report := stats.Report(10)
+
+	s.Equal("2s", report.Interval)
+	s.LessOrEqual(len(report.PerInterval), MaxIntervals)
+	s.Equal(IntervalCount{Time: s.start, Count: 1}, report.PerInterval[0])
+	s.Equal(IntervalCount{Time: s.start.Add(5 * time.Hour), Count: 1}, report.PerInterval[len(report.PerInterval)-1])
+}
+
+func (s *statsSuite) Test_CheckInterval() {
+	tests := []struct {
+		name          string
+		cfg           logging.ReaderConfig
+		interval      time.Duration
+		expectedError string
+	}{
+		{
+			name:     "Since",
+			cfg:      logging.ReaderConfig{Since: 24 * time.Hour},
+			interval: time.Minute,
+		},
+		{
+			name:     "From To",
+			cfg:      logging.ReaderConfig{From: s.start, To: s.start.Add(time.Hour)},
+			interval: time.Second,
+		},
+		{
+			name:     "Open Window",
+			cfg:      logging.ReaderConfig{From: s.start},
+			interval: time.Second,
+		},
+		{
+			name:          "Too Many Intervals",
+			cfg:           logging.ReaderConfig{Since: 24 * time.Hour},
+			interval:      time.Second,
+			expectedError: "interval 1s over 24h0m0s: more than 10000 intervals: too many intervals",
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			err := CheckInterval(test.cfg, test.interval)
+
+			if test.expectedError == "" {
+				s.NoError(err)
+			} else {
+				s.EqualError(err, test.expectedError)
+			}
+		})
+	}
+}
+
+func (s *statsSuite) Test_WriteTable() {
+	buf := &bytes.Buffer{}
+
+	err := s.expectedReport().WriteTable(buf)
+
+	s.NoError(err)
+	s.Equal(`Stats                 exact
+From                  2022-03-03T02:40:10Z
+To                    2022-03-03T02:43:20Z
+Requests              5
+Bytes                 360
+Unique paths          3
+Unique IPs            3
+Bytes p50/p90/p99     50/160/196
+Duration p50/p90/p99  20.00ms/28.00ms/29.80ms
+
+PATH         REQUESTS
+/api/users   3
+/api/orders  1
+
+IP        REQUESTS
+10.0.0.1  3
+10.0.0.2  1
+
+STATUS  REQUESTS
+200     2
+302     1
+404     1
+500     1
+
+TIME (1m0s)           REQUESTS
+2022-03-03T02:40:00Z  2
+2022-03-03T02:41:00Z  1
+2022-03-03T02:42:00Z  0
+2022-03-03T02:43:00Z  2
+`, buf.String())
+}
+
+func (s *statsSuite) expectedReport() Report {
+	return Report{
+		From:              s.start.Add(10 * time.Second),
+		To:                s.start.Add(200 * time.Second),
+		Requests:          5,
+		Bytes:             360,
+		UniquePaths:       3,
+		UniqueIPs:         3,
+		TopPaths:          []Count{{Value: "/api/users", Count: 3}, {Value: "/api/orders", Count: 1}},
+		TopIPs:            []Count{{Value: "10.0.0.1", Count: 3}, {Value: "10.0.0.2", Count: 1}},
+		BytesQuantiles:    Quantiles{P50: 50, P90: 160, P99: 196},
+		DurationQuantiles: Quantiles{P50: 20, P90: 28, P99: 29.8},
+		Statuses: []StatusCount{
+			{Status: 200, Count: 2},
+			{Status: 302, Count: 1},
+			{Status: 404, Count: 1},
+			{Status: 500, Count: 1},
+		},
+		Interval: "1m0s",
+		PerInterval: []IntervalCount{
+			{Time: s.start, Count: 2},
+			{Time: s.start.Add(time.Minute), Count: 1},
+			{Time: s.start.Add(2 * time.Minute), Count: 0},
+			{Time: s.start.Add(3 * time.Minute), Count: 2},
+		},
+	}
+}
+
+func TestStats(t *testing.T) {
+	suite.Run(t, new(statsSuite))
+}
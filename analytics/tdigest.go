@@ -0,0 +1,138 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+)
+
+// DefaultCompression is the default t-digest compression, keeping at most ~2*compression centroids
+const DefaultCompression = 100
+
+// NewTDigest creates a t-digest sketch estimating the quantiles of a distribution, i.e. response sizes or latencies.
+// The sketch keeps O(compression) centroids, which are smaller towards the tails of the distribution,
+// so extreme quantiles are more accurate than the median: with the default compression of 100
+// the rank error is typically below 0.5% for the median and below 0.05% for the p99
+func NewTDigest(compression float64) *TDigest {
+	if compression <= 0 {
+		compression = DefaultCompression
+	}
+	return &TDigest{
+		compression: compression,
+		min:         math.Inf(1),
+		max:         math.Inf(-1),
+	}
+}
+
+// TDigest represents a merging t-digest sketch
+type TDigest struct {
+	compression float64
+	centroids   []centroid
+	buffer      []centroid
+	count       float64
+	min         float64
+	max         float64
+}
+
+type centroid struct {
+	mean   float64
+	weight float64
+}
+
+// Add adds a value to the sketch
+func (t *TDigest) Add(value float64) {
+	t.add(centroid{mean: value, weight: 1})
+}
+
+func (t *TDigest) add(c centroid) {
+	t.buffer = append(t.buffer, c)
+	t.count += c.weight
+	t.min = math.Min(t.min, c.mean)
+	t.max = math.Max(t.max, c.mean)
+	if len(t.buffer) >= int(10*t.compression) {
+		t.compress()
+	}
+}
+
+// Quantile returns the estimated value at the given quantile (0 <= q <= 1), i.e. 0.99 for the p99.
+// 0 is returned when no values were added
+func (t *TDigest) Quantile(q float64) float64 {
+	t.compress()
+	if len(t.centroids) == 0 {
+		return 0
+	}
+	if q <= 0 {
+		return t.min
+	}
+	if q >= 1 {
+		return t.max
+	}
+
+	// the mean of every centroid is considered to be at the middle of its rank range,
+	// values between the centroids are linearly interpolated
+	rank := q * t.count
+	cumulative := 0.0
+	previousMean, previousRank := t.min, 0.0
+	for _, c := range t.centroids {
+		middle := cumulative + c.weight/2
+		if rank < middle {
+			return interpolate(rank, previousRank, middle, previousMean, c.mean)
+		}
+		cumulative += c.weight
+		previousMean, previousRank = c.mean, middle
+	}
+	return interpolate(rank, previousRank, t.count, previousMean, t.max)
+}
+
+// Merge merges another sketch into the current one
+func (t *TDigest) Merge(other *TDigest) {
+	if other.count == 0 {
+		return
+	}
+
+	other.compress()
+	for _, c := range other.centroids {
+		t.add(c)
+	}
+	// the centroids only know the mean of their values, the extremes are kept apart
+	t.min = math.Min(t.min, other.min)
+	t.max = math.Max(t.max, other.max)
+}
+
+// compress merges the buffered values into the centroids.
+// Neighbouring centroids are merged as long as the merged centroid weighs less than 4*N*q*(1-q)/compression,
+// q being the quantile of the merged centroid, so the centroids are smaller towards the tails
+func (t *TDigest) compress() {
+	if len(t.buffer) == 0 {
+		return
+	}
+
+	all := append(t.centroids, t.buffer...)
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].mean < all[j].mean
+	})
+
+	merged := []centroid{all[0]}
+	before := 0.0
+	for _, c := range all[1:] {
+		last := &merged[len(merged)-1]
+		q := (before + (last.weight+c.weight)/2) / t.count
+		limit := 4 * t.count * q * (1 - q) / t.compression
+		if last.weight+c.weight <= math.Max(limit, 1) {
+			last.weight += c.weight
+			last.mean += (c.mean - last.mean) * c.weight / last.weight
+			continue
+		}
+		before += last.weight
+		merged = append(merged, c)
+	}
+
+	t.centroids = merged
+	t.buffer = t.buffer[:0]
+}
+
+func interpolate(x, x0, x1, y0, y1 float64) float64 {
+	if x1 <= x0 {
+		return y1
+	}
+	return y0 + (y1-y0)*(x-x0)/(x1-x0)
+}
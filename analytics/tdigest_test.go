@@ -0,0 +1,82 @@
+package analytics
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type tDigestSuite struct {
+	suite.Suite
+}
+
+func (s *tDigestSuite) Test_Quantile() {
+	random := rand.New(rand.NewSource(42))
+	digest := NewTDigest(DefaultCompression)
+	values := make([]float64, 0, 100000)
+	for i := 0; i < 100000; i++ {
+		// long tailed latencies, like most response times
+		value := random.ExpFloat64() * 100
+		values = append(values, value)
+		digest.Add(value)
+	}
+	sort.Float64s(values)
+
+	tests := []struct {
+		quantile     float64
+		maxRankError float64
+	}{
+		{quantile: 0.5, maxRankError: 0.005},
+		{quantile: 0.9, maxRankError: 0.003},
+		{quantile: 0.99, maxRankError: 0.0005},
+		{quantile: 0.999, maxRankError: 0.0002},
+	}
+	for _, test := range tests {
+		estimate := digest.Quantile(test.quantile)
+		rank := float64(sort.SearchFloat64s(values, estimate)) / float64(len(values))
+
+		s.InDelta(test.quantile, rank, test.maxRankError, "quantile %v", test.quantile)
+	}
+	s.Equal(values[0], digest.Quantile(0))
+	s.Equal(values[len(values)-1], digest.Quantile(1))
+}
+
+func (s *tDigestSuite) Test_Quantile_Empty() {
+	s.Equal(float64(0), NewTDigest(0).Quantile(0.5))
+}
+
+func (s *tDigestSuite) Test_Merge() {
+	first, second := NewTDigest(DefaultCompression), NewTDigest(DefaultCompression)
+	for i := 0; i < 50000; i++ {
+		first.Add(float64(i))
+		second.Add(float64(50000 + i))
+	}
+
+	first.Merge(second)
+
+	s.InDelta(50000, first.Quantile(0.5), 500)
+	s.InDelta(99000, first.Quantile(0.99), 100)
+}
+
+func (s *tDigestSuite) Test_Merge_Extremes() {
+	// a low compression makes the extremes of the merged sketch end up inside centroids merging several values
+	first, second := NewTDigest(DefaultCompression), NewTDigest(1)
+	for i := 0; i < 1000; i++ {
+		first.Add(float64(i))
+		second.Add(float64(1000 + i))
+	}
+	second.Add(-5)
+	second.Add(5000)
+
+	first.Merge(second)
+	first.Merge(NewTDigest(DefaultCompression))
+
+	s.Equal(float64(-5), first.Quantile(0))
+	s.Equal(float64(5000), first.Quantile(1))
+}
+
+func TestTDigest(t *testing.T) {
+	suite.Run(t, new(tDigestSuite))
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/steevehook/weblog-analytics/logging"
+)
+
+// compress compresses a log file into a seekable gzip archive alongside its sidecar index,
+// i.e. log-reader compress -frame-size 4194304 access.log -> access.log.gz + access.log.gz.idx
+func compress(args []string) {
+	fs := flag.NewFlagSet("compress", flag.ExitOnError)
+	frameSizeFlag := fs.Int("frame-size", logging.DefaultFrameSize, "the amount of uncompressed logs (in bytes) stored in each independently compressed frame")
+	outputFlag := fs.String("o", "", "the output archive, defaults to the log file name with the .gz extension")
+	newParser := parserFlags(fs)
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		log.Fatalf("usage: log-reader compress [flags] <log-file>")
+	}
+	name := fs.Arg(0)
+	output := *outputFlag
+	if output == "" {
+		output = name + ".gz"
+	}
+
+	parser, err := newParser()
+	if err != nil {
+		log.Fatalf("could not create log parser: %v", err)
+	}
+
+	index, err := logging.CompressFile(name, output, parser, *frameSizeFlag)
+	if err != nil {
+		log.Fatalf("could not compress log file: %v", err)
+	}
+
+	log.Printf("compressed %s into %s: %d frames", name, output, len(index.Entries))
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/steevehook/weblog-analytics/logging"
+)
+
+// readerFlags defines the log reader flags (directory, time window, log format, filter, etc.) on the given flag set
+// and returns a function creating the log reader configuration once the flags are parsed
+func readerFlags(fs *flag.FlagSet) func() (logging.ReaderConfig, error) {
+	directoryFlag := fs.String("d", ".", "the directory where all the logs are stored")
+	since := durationFlag(time.Minute)
+	fs.Var(&since, "t", "last T time worth of logs to read, i.e. 5 (minutes), 90s, 2m30s")
+	fs.Var(&since, "since", "alias for -t")
+	fromFlag := fs.String("from", "", "the beginning of the time window to read logs from (RFC3339), takes precedence over -t")
+	toFlag := fs.String("to", "", "the end of the time window to read logs till (RFC3339)")
+	mergeFlag := fs.Bool("merge", false, "merge log files overlapping in time (i.e. multiple hosts) ordering all the logs by time")
+	filterFlag := fs.String("filter", "", `keep only the logs matching the filter, i.e. status >= 500 && path =~ "^/api/"`)
+	orderFlag := fs.String("order", logging.OrderModTime, "the order the log files are read in: mtime, content (the time of their first and last logs)")
+	maxLineSizeFlag := fs.Int("max-line-size", logging.DefaultMaxLineSize, "the maximum size of a log line in bytes")
+	longLinesFlag := fs.String("long-lines", logging.LongLinesFail, "what happens with the lines longer than -max-line-size: fail, skip (with a warning)")
+	malformedFlag := fs.String("malformed", logging.MalformedFail, "what happens with the lines that can't be parsed: fail, skip, quarantine (write them to -quarantine)")
+	quarantineFlag := fs.String("quarantine", "", "the file the malformed lines are appended to, with -malformed quarantine")
+	workersFlag := fs.Int("workers", 1, "the number of workers parsing and filtering the logs of big files in parallel, i.e. the number of CPUs, the output stays in order")
+	newParser := parserFlags(fs)
+
+	return func() (logging.ReaderConfig, error) {
+		from, err := parseTime(*fromFlag)
+		if err != nil {
+			return logging.ReaderConfig{}, fmt.Errorf("could not parse from time: %w", err)
+		}
+		to, err := parseTime(*toFlag)
+		if err != nil {
+			return logging.ReaderConfig{}, fmt.Errorf("could not parse to time: %w", err)
+		}
+
+		parser, err := newParser()
+		if err != nil {
+			return logging.ReaderConfig{}, fmt.Errorf("could not create log parser: %w", err)
+		}
+
+		var filter *logging.Filter
+		if *filterFlag != "" {
+			filter, err = logging.ParseFilter(*filterFlag)
+			if err != nil {
+				return logging.ReaderConfig{}, fmt.Errorf("could not parse filter: %w", err)
+			}
+		}
+
+		var quarantine io.Writer
+		if *malformedFlag == logging.MalformedQuarantine && *quarantineFlag != "" {
+			quarantine, err = os.OpenFile(*quarantineFlag, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+			if err != nil {
+				return logging.ReaderConfig{}, fmt.Errorf("could not open quarantine file: %w", err)
+			}
+		}
+
+		cfg := logging.ReaderConfig{
+			Directory:   *directoryFlag,
+			Since:       time.Duration(since),
+			From:        from,
+			To:          to,
+			Parser:      parser,
+			Merge:       *mergeFlag,
+			Filter:      filter,
+			Order:       *orderFlag,
+			MaxLineSize: *maxLineSizeFlag,
+			LongLines:   *longLinesFlag,
+			Malformed:   *malformedFlag,
+			Quarantine:  quarantine,
+			Workers:     *workersFlag,
+			Logger:      log.Default(),
+		}
+		return cfg, nil
+	}
+}
+
+// closeQuarantine closes the quarantine file the malformed lines were appended to, if any
+func closeQuarantine(cfg logging.ReaderConfig) {
+	closer, ok := cfg.Quarantine.(io.Closer)
+	if !ok {
+		return
+	}
+	err := closer.Close()
+	if err != nil {
+		log.Fatalf("could not close quarantine file: %v", err)
+	}
+}
+
+// parserFlags defines the log format flags on the given flag set
+// and returns a function creating the log parser once the flags are parsed
+func parserFlags(fs *flag.FlagSet) func() (logging.LineParser, error) {
+	parserFlag := fs.String("parser", "apache", "the log format parser: "+strings.Join(logging.ParserNames(), ", "))
+	formatFlag := fs.String("format", "", "custom apache LogFormat or nginx log_format of the logs, takes precedence over -parser")
+	timeFieldFlag := fs.String("time-field", "", "the dot separated path to the time field of jsonl logs, i.e. ts or request.time")
+	timeFormatFlag := fs.String("time-format", logging.JSONTimeAuto, "the time format of jsonl logs: auto, rfc3339, unix, unixmilli, unixmicro, unixnano or a Go time layout")
+
+	return func() (logging.LineParser, error) {
+		if *formatFlag != "" {
+			return logging.CompileFormat(*formatFlag)
+		}
+		if *parserFlag == "jsonl" {
+			return logging.NewJSONParser(*timeFieldFlag, *timeFormatFlag), nil
+		}
+		return logging.ParserByName(*parserFlag)
+	}
+}
+
+// parseTime parses the time flags, accepting both RFC3339
+// and a shorter UTC form like: 2022-03-07 14:00:00
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02 15:04:05", value)
+}
+
+// durationFlag is a time.Duration flag that also accepts
+// plain numbers as minutes, to stay compatible with the old -t flag
+type durationFlag time.Duration
+
+func (d *durationFlag) String() string {
+	return time.Duration(*d).String()
+}
+
+func (d *durationFlag) Set(value string) error {
+	duration, err := logging.ParseSince(value)
+	if err != nil {
+		return err
+	}
+	*d = durationFlag(duration)
+	return nil
+}
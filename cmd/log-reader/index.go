@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/steevehook/weblog-analytics/logging"
+)
+
+// index builds or extends the sidecar index of plain log files, so their time windows are found faster,
+// i.e. log-reader index -interval 262144 access.log -> access.log.idx
+// Running it again, i.e. periodically, only indexes the logs appended since the last run
+func index(args []string) {
+	fs := flag.NewFlagSet("index", flag.ExitOnError)
+	intervalFlag := fs.Int64("interval", logging.DefaultIndexInterval, "the amount of logs (in bytes) between two index entries")
+	newParser := parserFlags(fs)
+	_ = fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		log.Fatalf("usage: log-reader index [flags] <log-file>...")
+	}
+	parser, err := newParser()
+	if err != nil {
+		log.Fatalf("could not create log parser: %v", err)
+	}
+
+	for _, name := range fs.Args() {
+		idx, err := logging.IndexFile(name, parser, *intervalFlag)
+		if err != nil {
+			log.Fatalf("could not index log file: %v", err)
+		}
+		log.Printf("indexed %s: %d entries, %d bytes", name, len(idx.Entries), idx.Size)
+	}
+}
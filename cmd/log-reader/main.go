@@ -12,32 +12,83 @@ import (
 )
 
 func main() {
-	quit := make(chan os.Signal, 1)
-	directoryFlag := flag.String("d", ".", "the directory where all the logs are stored")
-	minutesFlag := flag.Int("t", 1, "last n minutes of worth of logs to read")
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "compress":
+			compress(os.Args[2:])
+			return
+		case "index":
+			index(os.Args[2:])
+			return
+		case "stats":
+			stats(os.Args[2:])
+			return
+		case "serve":
+			serve(os.Args[2:])
+			return
+		}
+	}
+
+	followFlag := flag.Bool("f", false, "keep reading the new logs, surviving log rotation, till interrupted")
+	reverseFlag := flag.Bool("reverse", false, "read the logs newest first, from the end of the time window backwards")
+	limitFlag := flag.Int("n", 0, "stop after reading N logs (the ones matching -filter), the latest ones with -reverse, 0 means no limit")
+	outputFlag := flag.String("o", logging.OutputRaw, "the output format: raw (the log lines), json, ndjson, csv")
+	templateFlag := flag.String("template", "", `Go template executed for every parsed log, i.e. '{{.Time}} {{.Status}} {{.Path}}', takes precedence over -o`)
+	stateFlag := flag.String("state", "", "the file storing where the reading stopped, so the next run only reads the logs written since, i.e. from cron")
+	newConfig := readerFlags(flag.CommandLine)
 
 	flag.Parse()
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
-	ctx, cancel := context.WithCancel(context.Background())
-	cfg := logging.ReaderConfig{
-		Directory:    *directoryFlag,
-		LastNMinutes: *minutesFlag,
+	cfg, err := newConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	cfg.Follow = *followFlag
+	cfg.Reverse = *reverseFlag
+	cfg.Limit = *limitFlag
+	if *stateFlag != "" {
+		cfg.Checkpoint, err = readState(*stateFlag)
+		if err != nil {
+			log.Fatalf("could not read state: %v", err)
+		}
 	}
+
+	// the reading stops as soon as interrupted, so the state is stored when following as well
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
 	logReader, err := logging.NewReader(cfg)
 	if err != nil {
 		log.Fatalf("could not create log reader: %v", err)
 	}
 
-	go func() {
-		err := logReader.Read(ctx, os.Stdout)
-		if err != nil {
-			log.Fatalf("could not read logs: %v", err)
-		}
+	var writer logging.EntryWriter
+	switch {
+	case *templateFlag != "":
+		writer, err = logging.NewTemplateWriter(os.Stdout, *templateFlag)
+	case *outputFlag != logging.OutputRaw:
+		writer, err = logging.NewEntryWriter(os.Stdout, *outputFlag)
+	}
+	if err != nil {
+		log.Fatalf("could not create output writer: %v", err)
+	}
 
-		quit <- os.Interrupt
-	}()
+	if writer == nil {
+		err = logReader.Read(ctx, os.Stdout)
+	} else {
+		err = logReader.ReadEntries(ctx, writer.Write)
+		if err == nil {
+			err = writer.Close()
+		}
+	}
+	if err != nil {
+		log.Fatalf("could not read logs: %v", err)
+	}
+	closeQuarantine(cfg)
 
-	<-quit
-	cancel()
+	if *stateFlag != "" {
+		err = writeState(*stateFlag, logReader)
+		if err != nil {
+			log.Fatalf("could not write state: %v", err)
+		}
+	}
 }
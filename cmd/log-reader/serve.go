@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/steevehook/weblog-analytics/server"
+)
+
+// serve exposes the logs and the stats over HTTP till interrupted,
+// i.e. log-reader serve -addr :8080 -d /var/log/nginx
+func serve(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addrFlag := fs.String("addr", ":8080", "the address the HTTP query API listens on")
+	newConfig := readerFlags(fs)
+	_ = fs.Parse(args)
+
+	cfg, err := newConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+	httpServer := &http.Server{
+		Addr: *addrFlag,
+		Handler: server.New(server.Config{
+			Reader: cfg,
+			Logger: log.Default(),
+		}),
+		BaseContext: func(_ net.Listener) context.Context {
+			return ctx
+		},
+	}
+
+	// the requests share the signal context, so the streaming ones stop on shutdown as well
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			log.Printf("could not shutdown server: %v", err)
+		}
+	}()
+
+	log.Printf("serving the logs of '%s' on %s", cfg.Directory, *addrFlag)
+	err = httpServer.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("could not serve: %v", err)
+	}
+	<-done
+	closeQuarantine(cfg)
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"os"
+
+	"github.com/steevehook/weblog-analytics/logging"
+)
+
+// readState reads the checkpoint stored by the previous run, nil is returned on the very first run
+func readState(name string) (*logging.Checkpoint, error) {
+	checkpoint, err := logging.ReadCheckpoint(name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return checkpoint, err
+}
+
+// writeState stores the checkpoint of the last log read, for the next run to resume from
+func writeState(name string, reader *logging.Reader) error {
+	checkpoint, err := reader.Checkpoint()
+	if err != nil || checkpoint == nil {
+		return err
+	}
+	return checkpoint.WriteFile(name)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/steevehook/weblog-analytics/analytics"
+	"github.com/steevehook/weblog-analytics/logging"
+)
+
+// stats aggregates the logs of the time window in a single pass and prints the report,
+// i.e. log-reader stats -d /var/log/nginx -t 15m -top 10 -interval 1m -json
+func stats(args []string) {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	topFlag := fs.Int("top", 10, "the number of top paths and client IPs to report")
+	intervalFlag := fs.Duration("interval", analytics.DefaultInterval, "the interval the requests are counted per")
+	jsonFlag := fs.Bool("json", false, "print the report as JSON instead of tables")
+	approxFlag := fs.Bool("approx", false, "estimate the unique and top paths and IPs and the quantiles using constant memory sketches")
+	capacityFlag := fs.Int("capacity", analytics.DefaultCapacity, "the number of paths and IPs tracked by the approximate top sketches")
+	newConfig := readerFlags(fs)
+	_ = fs.Parse(args)
+
+	cfg, err := newConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = analytics.CheckInterval(cfg, *intervalFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	logReader, err := logging.NewReader(cfg)
+	if err != nil {
+		log.Fatalf("could not create log reader: %v", err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+	// every worker aggregates its own partial stats, merged once the logs were read
+	aggregator, err := analytics.Aggregate(ctx, logReader, func() *analytics.Stats {
+		if *approxFlag {
+			return analytics.NewApproxStats(*intervalFlag, *capacityFlag)
+		}
+		return analytics.NewStats(*intervalFlag)
+	})
+	if err != nil {
+		log.Fatalf("could not read logs: %v", err)
+	}
+	closeQuarantine(cfg)
+
+	report := aggregator.Report(*topFlag)
+	if *jsonFlag {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	} else {
+		err = report.WriteTable(os.Stdout)
+	}
+	if err != nil {
+		log.Fatalf("could not write report: %v", err)
+	}
+}
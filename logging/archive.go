@@ -0,0 +1,157 @@
+package logging
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultFrameSize is the default amount of uncompressed logs stored in each frame of a seekable archive
+const DefaultFrameSize = 4 * 1024 * 1024 // 4MB
+
+// Compress compresses the src logs into dst as a seekable gzip archive:
+// a series of independent gzip members (frames) of about frameSize uncompressed bytes each, split at line boundaries.
+// The archive is a valid multi member gzip file, readable by any gzip tool.
+// The returned index maps the time of the first log of each frame to the frame offset inside the archive
+func Compress(dst io.Writer, src io.Reader, parser LineParser, frameSize int) (*TimeIndex, error) {
+	if frameSize <= 0 {
+		frameSize = DefaultFrameSize
+	}
+
+	counter := &countingWriter{w: dst}
+	index := &TimeIndex{}
+	reader := bufio.NewReader(src)
+	var frame *gzip.Writer
+	var frameOffset int64
+	frameLen, frameIndexed := 0, false
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if line == "" {
+			break
+		}
+
+		if frame == nil {
+			frameOffset = counter.n
+			frame = gzip.NewWriter(counter)
+			frameLen, frameIndexed = 0, false
+		}
+		// index the frame using the first log that has a valid time
+		if !frameIndexed && strings.TrimSpace(line) != "" {
+			logTime, parseErr := parser.ParseTime(strings.TrimRight(line, "\r\n"))
+			if parseErr == nil {
+				index.Entries = append(index.Entries, IndexEntry{Time: logTime, Offset: frameOffset})
+				frameIndexed = true
+			}
+		}
+
+		_, writeErr := frame.Write([]byte(line))
+		if writeErr != nil {
+			return nil, writeErr
+		}
+		frameLen += len(line)
+		if frameLen >= frameSize {
+			closeErr := frame.Close()
+			if closeErr != nil {
+				return nil, closeErr
+			}
+			frame = nil
+		}
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	if frame != nil {
+		err := frame.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	index.Size = counter.n
+	return index, nil
+}
+
+// CompressFile compresses the named log file into the output seekable gzip archive alongside its sidecar index.
+// The archive keeps the modification time of the log file, so it's still ordered by the time of its last log
+// among the other log files, i.e. access.log -> access.log.gz + access.log.gz.idx
+func CompressFile(name, output string, parser LineParser, frameSize int) (*TimeIndex, error) {
+	src, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = src.Close() }()
+	stat, err := src.Stat()
+	if err != nil {
+		return nil, err
+	}
+	dst, err := os.Create(output)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := Compress(dst, src, parser, frameSize)
+	if err != nil {
+		_ = dst.Close()
+		return nil, err
+	}
+	err = dst.Close()
+	if err != nil {
+		return nil, err
+	}
+	err = index.WriteFile(output)
+	if err != nil {
+		return nil, err
+	}
+	err = os.Chtimes(output, stat.ModTime(), stat.ModTime())
+	if err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// seekArchive looks up the sidecar index of a seekable gzip archive
+// and returns a decompressed reader starting at the frame the lookup time may be found in.
+// ok == false -> the archive has no valid index and has to be read from the very beginning
+func seekArchive(file *os.File, lookupTime time.Time) (r io.Reader, ok bool, err error) {
+	index, err := ReadIndex(file.Name())
+	if err != nil {
+		// no index, or an index that can't be used, is not an error, it's just slower
+		return nil, false, nil
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, false, err
+	}
+	if stat.Size() != index.Size {
+		return nil, false, nil
+	}
+
+	_, err = file.Seek(index.lookup(lookupTime), io.SeekStart)
+	if err != nil {
+		return nil, false, err
+	}
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, false, err
+	}
+	return gz, true, nil
+}
+
+// countingWriter counts the number of bytes written to the underlying writer
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
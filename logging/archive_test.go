@@ -0,0 +1,162 @@
+package logging
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type archiveSuite struct {
+	suite.Suite
+	logs  string
+	start time.Time
+}
+
+func (s *archiveSuite) SetupSuite() {
+	start, err := time.Parse(dateTimeFormat, "03/Mar/2022:02:00:00 +0000")
+	s.Require().NoError(err)
+	s.start = start
+	s.logs = endpointLogs(start, 0, 100)
+}
+
+func (s *archiveSuite) Test_Compress_Success() {
+	archive := &bytes.Buffer{}
+
+	// each log line is 98 bytes long, so each frame has 10 logs
+	index, err := Compress(archive, strings.NewReader(s.logs), NewApacheParser(), 980)
+	s.Require().NoError(err)
+
+	s.Equal(int64(archive.Len()), index.Size)
+	s.Len(index.Entries, 10)
+	s.Equal(int64(0), index.Entries[0].Offset)
+	for i, entry := range index.Entries {
+		s.True(entry.Time.Equal(s.start.Add(time.Duration(i*10)*time.Second)), entry.Time.String())
+
+		// every frame is an independent gzip member starting with the log the frame is indexed by
+		gz, err := gzip.NewReader(bytes.NewReader(archive.Bytes()[entry.Offset:]))
+		s.Require().NoError(err)
+		gz.Multistream(false)
+		frame, err := io.ReadAll(gz)
+		s.Require().NoError(err)
+		s.True(strings.HasPrefix(string(frame), endpointLogs(s.start, i*10, i*10+1)))
+	}
+
+	// the whole archive is a valid multi member gzip
+	gz, err := gzip.NewReader(bytes.NewReader(archive.Bytes()))
+	s.Require().NoError(err)
+	content, err := io.ReadAll(gz)
+	s.NoError(err)
+	s.Equal(s.logs, string(content))
+}
+
+func (s *archiveSuite) Test_Compress_DefaultFrameSize() {
+	archive := &bytes.Buffer{}
+
+	index, err := Compress(archive, strings.NewReader(s.logs), NewApacheParser(), 0)
+
+	s.NoError(err)
+	s.Len(index.Entries, 1)
+}
+
+func (s *archiveSuite) Test_seekArchive() {
+	f, index := s.createArchive(980)
+	defer func() { s.Require().NoError(f.Close()) }()
+
+	r, ok, err := seekArchive(f, s.start.Add(35*time.Second))
+	s.Require().NoError(err)
+	s.Require().True(ok)
+	content, err := io.ReadAll(r)
+
+	s.NoError(err)
+	s.Equal(s.logs[30*98:], string(content))
+
+	// an outdated index is not used
+	index.Size++
+	s.Require().NoError(index.WriteFile(f.Name()))
+	r, ok, err = seekArchive(f, s.start.Add(35*time.Second))
+	s.NoError(err)
+	s.False(ok)
+	s.Nil(r)
+}
+
+func (s *archiveSuite) Test_Read_SeekableArchive() {
+	dir := s.T().TempDir()
+	f, _ := s.createArchive(980)
+	s.Require().NoError(f.Close())
+	s.Require().NoError(os.Rename(f.Name(), path.Join(dir, "http.log.gz")))
+	s.Require().NoError(os.Rename(f.Name()+indexExtension, path.Join(dir, "http.log.gz"+indexExtension)))
+	end := s.start.Add(100 * time.Second)
+	s.Require().NoError(os.Chtimes(path.Join(dir, "http.log.gz"), end, end))
+
+	// corrupt the first frame, which must never be read thanks to the index
+	archive, err := os.OpenFile(path.Join(dir, "http.log.gz"), os.O_RDWR, 0)
+	s.Require().NoError(err)
+	_, err = archive.WriteAt([]byte("corrupted"), 20)
+	s.Require().NoError(err)
+	s.Require().NoError(archive.Close())
+
+	reader, err := NewReader(ReaderConfig{
+		Directory: dir,
+		From:      s.start.Add(42 * time.Second),
+		To:        s.start.Add(44 * time.Second),
+	})
+	s.Require().NoError(err)
+	buf := &bytes.Buffer{}
+
+	err = reader.Read(context.Background(), buf)
+
+	s.NoError(err)
+	s.Equal(endpointLogs(s.start, 42, 45), buf.String())
+}
+
+func (s *archiveSuite) Test_CompressFile_KeepsModificationTime() {
+	dir := s.T().TempDir()
+	rotated := path.Join(dir, "http.log.1")
+	writeTestLogs(s.T(), rotated, testLogs(s.start, 0, 50), s.start.Add(50*time.Second))
+	writeTestLogs(s.T(), path.Join(dir, "http.log"), testLogs(s.start, 50, 100), s.start.Add(100*time.Second))
+
+	index, err := CompressFile(rotated, rotated+".gz", NewApacheParser(), 980)
+	s.Require().NoError(err)
+	s.Require().NoError(os.Remove(rotated))
+	stat, err := os.Stat(rotated + ".gz")
+	s.Require().NoError(err)
+	s.True(stat.ModTime().Equal(s.start.Add(50*time.Second)), stat.ModTime().String())
+	stored, err := ReadIndex(rotated + ".gz")
+	s.Require().NoError(err)
+	s.Equal(index.Size, stored.Size)
+
+	reader, err := NewReader(ReaderConfig{
+		Directory: dir,
+		From:      s.start.Add(45 * time.Second),
+		To:        s.start.Add(54 * time.Second),
+	})
+	s.Require().NoError(err)
+	buf := &bytes.Buffer{}
+
+	err = reader.Read(context.Background(), buf)
+
+	s.NoError(err)
+	s.Equal(testLogs(s.start, 45, 55), buf.String())
+}
+
+// createArchive compresses the suite logs into a seekable archive alongside its index
+func (s *archiveSuite) createArchive(frameSize int) (*os.File, *TimeIndex) {
+	f, err := os.CreateTemp(s.T().TempDir(), "*-http.log.gz")
+	s.Require().NoError(err)
+	index, err := Compress(f, strings.NewReader(s.logs), NewApacheParser(), frameSize)
+	s.Require().NoError(err)
+	s.Require().NoError(index.WriteFile(f.Name()))
+	return f, index
+}
+
+func TestArchive(t *testing.T) {
+	suite.Run(t, new(archiveSuite))
+}
@@ -0,0 +1,331 @@
+package logging
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path"
+	"time"
+)
+
+// Checkpoint represents the position of the last log read, so the next read resumes right after it,
+// i.e. a reader running periodically from cron emits every log exactly once, even across log rotations
+type Checkpoint struct {
+	// File is the name of the log file the last log was read from
+	File string `json:"file"`
+	// Device and Inode identify the log file even after it was renamed by a log rotation,
+	// they're 0 on the platforms not supporting them, in which case the log file is identified by its name
+	Device uint64 `json:"device"`
+	Inode  uint64 `json:"inode"`
+	// Head is the hash of the first line of the log file, telling apart a new log file reusing the inode
+	// of a deleted one or a log file that was truncated (copytruncate rotation) and written again
+	Head string `json:"head"`
+	// Offset is the offset of the last log inside the log file, -1 for compressed log files
+	Offset int64 `json:"offset"`
+	// Time is the time of the last log, the logs of the same time and newer are read once the log file can't be found anymore
+	Time time.Time `json:"time"`
+	// Last is the hash of the last log, telling it apart from the other logs of the same time
+	Last string `json:"last"`
+}
+
+// ReadCheckpoint reads a checkpoint stored by Checkpoint.WriteFile
+func ReadCheckpoint(name string) (*Checkpoint, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoint Checkpoint
+	err = json.Unmarshal(data, &checkpoint)
+	if err != nil {
+		return nil, fmt.Errorf("checkpoint '%s': %w", name, err)
+	}
+	return &checkpoint, nil
+}
+
+// WriteFile stores the checkpoint in the given file.
+// The checkpoint is written next to it first, then renamed, so a crash never leaves a partially written checkpoint behind
+func (checkpoint *Checkpoint) WriteFile(name string) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	tmp := name + ".tmp"
+	err = os.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
+
+// Checkpoint returns the position of the last log read, to be passed as ReaderConfig.Checkpoint to the next read.
+// The checkpoint the reader was configured with is returned when no logs were read
+func (r *Reader) Checkpoint() (*Checkpoint, error) {
+	last := r.last
+	if last.File == "" {
+		return r.cfg.Checkpoint, nil
+	}
+
+	checkpoint := &Checkpoint{
+		File:   last.File,
+		Offset: last.Offset,
+		Last:   hash([]byte(last.Text)),
+	}
+	logTime, err := r.parser.ParseTime(last.Text)
+	if err == nil {
+		checkpoint.Time = logTime
+	}
+	if last.Offset < 0 {
+		return checkpoint, nil
+	}
+
+	f, err := os.Open(path.Join(r.cfg.Directory, last.File))
+	if os.IsNotExist(err) {
+		checkpoint.Offset = -1
+		return checkpoint, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	checkpoint.Device, checkpoint.Inode = fileID(stat)
+	checkpoint.Head, err = head(f)
+	if err != nil {
+		return nil, err
+	}
+
+	// the log file might have been rotated since the log was read (i.e. while following),
+	// in which case the offset is meaningless and the next read resumes using the time of the log
+	line, err := r.lineAt(f, last.Offset)
+	if err != nil {
+		return nil, err
+	}
+	if line != last.Text {
+		checkpoint.Offset = -1
+	}
+	return checkpoint, nil
+}
+
+// resume reads the logs following the checkpoint till the end of the time window,
+// starting inside the log file of the checkpoint, wherever it was rotated to.
+// resumed == false -> the log file of the checkpoint can't be found anymore, nothing was read
+func (r *Reader) resume(ctx context.Context, emit func(line Line) error, to time.Time) (resumed bool, err error) {
+	checkpoint := r.cfg.Checkpoint
+	if checkpoint.Offset < 0 {
+		return false, nil
+	}
+
+	for i, fi := range r.filesInfo {
+		found, windowEnded, err := r.readAfter(ctx, emit, fi, checkpoint, to)
+		if err != nil {
+			return false, err
+		}
+		if !found {
+			continue
+		}
+
+		if windowEnded {
+			return true, nil
+		}
+		return true, r.readOthers(ctx, emit, r.filesInfo[i+1:], time.Time{}, to)
+	}
+	return false, nil
+}
+
+// readAfter reads the logs following the checkpoint inside the given file, if it's the log file of the checkpoint.
+// found == false -> the file is not the log file of the checkpoint
+// windowEnded == true -> the window ends inside the log file, no need to read any other file
+func (r *Reader) readAfter(ctx context.Context, emit func(line Line) error, fi fileInfo, checkpoint *Checkpoint, to time.Time) (found, windowEnded bool, err error) {
+	f, err := os.Open(path.Join(r.cfg.Directory, fi.name))
+	if err != nil {
+		return false, false, err
+	}
+	defer func() { _ = f.Close() }()
+
+	found, err = checkpoint.matches(f, fi.name)
+	if err != nil || !found {
+		return false, false, err
+	}
+
+	end, err := r.windowEnd(ctx, r.newFile(f), to)
+	if err != nil {
+		return false, false, err
+	}
+	r.tail = position{name: fi.name, offset: end}
+	if checkpoint.Offset >= end {
+		return true, end < fi.size, nil
+	}
+
+	_, err = f.Seek(checkpoint.Offset, io.SeekStart)
+	if err != nil {
+		return false, false, err
+	}
+	lines := r.lines(ctx, io.LimitReader(f, end-checkpoint.Offset), fi.name, checkpoint.Offset)
+	// the first line is the log of the checkpoint, which was already read
+	_, err = lines.next()
+	if err != nil && err != io.EOF {
+		return false, false, err
+	}
+	for {
+		line, err := lines.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return false, false, err
+		}
+		err = emit(Line{Text: line, File: fi.name, Offset: lines.start})
+		if err != nil {
+			return false, false, err
+		}
+	}
+	return true, end < fi.size, nil
+}
+
+// readSince reads the logs following the time of the checkpoint, once its log file can't be found anymore.
+// There may be several logs of the same time as the checkpoint, read or not, so they are held back
+// till the log of the checkpoint is found among them: the ones up to it are dropped and the following ones are emitted.
+// When it's not found, i.e. the checkpoint was stored by a former version, they are all emitted
+func (r *Reader) readSince(ctx context.Context, emit func(line Line) error, to time.Time) error {
+	// the workers must neither drop the logs filtered out, the log of the checkpoint may be one of them,
+	// nor aggregate the logs that were already read, so they pass the lines as they are
+	parse := r.parseLines
+	r.parseLines = false
+	defer func() { r.parseLines = parse }()
+
+	since := &sinceCheckpoint{
+		checkpoint: r.cfg.Checkpoint,
+		parser:     r.parser,
+		emit:       emit,
+	}
+	err := r.readWindow(ctx, since.next, r.cfg.Checkpoint.Time, to)
+	if err != nil {
+		return err
+	}
+	return since.flush()
+}
+
+// sinceCheckpoint drops the logs of the same time as the checkpoint that were already read, see Reader.readSince
+type sinceCheckpoint struct {
+	checkpoint *Checkpoint
+	parser     LineParser
+	emit       func(line Line) error
+	held       []Line
+	done       bool
+}
+
+func (s *sinceCheckpoint) next(line Line) error {
+	if s.done {
+		return s.emit(line)
+	}
+
+	logTime, err := s.parser.ParseTime(line.Text)
+	if err == nil && logTime.After(s.checkpoint.Time) {
+		err := s.flush()
+		if err != nil {
+			return err
+		}
+		return s.emit(line)
+	}
+	// the logs up to the one of the checkpoint were already read,
+	// the last match wins when the log of the checkpoint was written more than once
+	if hash([]byte(line.Text)) == s.checkpoint.Last {
+		s.held = s.held[:0]
+		return nil
+	}
+	s.held = append(s.held, line)
+	return nil
+}
+
+// flush emits the logs held back, once all the logs of the same time as the checkpoint were read
+func (s *sinceCheckpoint) flush() error {
+	if s.done {
+		return nil
+	}
+	s.done = true
+	for _, line := range s.held {
+		err := s.emit(line)
+		if err != nil {
+			return err
+		}
+	}
+	s.held = nil
+	return nil
+}
+
+// matches checks whether the given file is the log file of the checkpoint,
+// the file must have the same identity and first line and must not have shrunk below the offset of the checkpoint
+func (checkpoint *Checkpoint) matches(f *os.File, name string) (bool, error) {
+	stat, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	device, inode := fileID(stat)
+	switch {
+	case inode != 0 && checkpoint.Inode != 0:
+		if device != checkpoint.Device || inode != checkpoint.Inode {
+			return false, nil
+		}
+	case name != checkpoint.File:
+		return false, nil
+	}
+	if stat.Size() <= checkpoint.Offset {
+		return false, nil
+	}
+
+	_, compressed, err := decompress(f)
+	if err != nil || compressed {
+		return false, err
+	}
+	_, err = f.Seek(0, io.SeekStart)
+	if err != nil {
+		return false, err
+	}
+	h, err := head(f)
+	if err != nil {
+		return false, err
+	}
+	return h == checkpoint.Head, nil
+}
+
+// lineAt returns the line found at the given offset of the log file
+func (r *Reader) lineAt(f *os.File, offset int64) (string, error) {
+	_, err := f.Seek(offset, io.SeekStart)
+	if err != nil {
+		return "", err
+	}
+
+	line, err := newLineReader(context.Background(), f, offset, r.maxLineSize(), nil).next()
+	if err == io.EOF || errors.Is(err, errLineTooLong) {
+		return "", nil
+	}
+	return line, err
+}
+
+// head returns the hash of the first line of the file, the first 4KB of it for longer lines
+func head(reader io.Reader) (string, error) {
+	line, err := bufio.NewReader(reader).ReadSlice('\n')
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return "", err
+	}
+
+	return hash(line), nil
+}
+
+// hash returns the hex encoded FNV-1a hash of the data
+func hash(data []byte) string {
+	h := fnv.New64a()
+	_, _ = h.Write(data)
+	return fmt.Sprintf("%016x", h.Sum64())
+}
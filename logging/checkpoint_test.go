@@ -0,0 +1,195 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type checkpointSuite struct {
+	suite.Suite
+	dir   string
+	start time.Time
+}
+
+func (s *checkpointSuite) SetupTest() {
+	start, err := time.Parse(dateTimeFormat, "03/Mar/2022:02:00:00 +0000")
+	s.Require().NoError(err)
+	s.start = start
+	s.dir = s.T().TempDir()
+	writeTestLogs(s.T(), path.Join(s.dir, "http.log"), testLogs(s.start, 0, 3), s.start.Add(time.Minute))
+}
+
+func (s *checkpointSuite) Test_Checkpoint_Resume() {
+	size := int64(len(testLogs(s.start, 0, 1)))
+	checkpoint := s.read(nil, "/0", "/1", "/2")
+	s.Equal("http.log", checkpoint.File)
+	s.Equal(2*size, checkpoint.Offset)
+	s.True(checkpoint.Time.Equal(s.start.Add(2*time.Second)), checkpoint.Time.String())
+	s.NotZero(checkpoint.Inode)
+	s.NotEmpty(checkpoint.Head)
+
+	appendTestLogs(s.T(), path.Join(s.dir, "http.log"), testLogs(s.start, 3, 5))
+	checkpoint = s.read(checkpoint, "/3", "/4")
+	s.Equal(4*size, checkpoint.Offset)
+
+	// nothing new was written, the checkpoint stays the same
+	s.Equal(checkpoint, s.read(checkpoint))
+}
+
+func (s *checkpointSuite) Test_Checkpoint_RenameRotation() {
+	checkpoint := s.read(nil, "/0", "/1", "/2")
+	appendTestLogs(s.T(), path.Join(s.dir, "http.log"), testLogs(s.start, 3, 4))
+	s.Require().NoError(os.Rename(path.Join(s.dir, "http.log"), path.Join(s.dir, "http.log.1")))
+	s.Require().NoError(os.Chtimes(path.Join(s.dir, "http.log.1"), s.start, s.start.Add(time.Minute)))
+	writeTestLogs(s.T(), path.Join(s.dir, "http.log"), testLogs(s.start, 4, 6), s.start.Add(2*time.Minute))
+
+	checkpoint = s.read(checkpoint, "/3", "/4", "/5")
+	s.Equal("http.log", checkpoint.File)
+	s.Equal(int64(len(testLogs(s.start, 4, 5))), checkpoint.Offset)
+}
+
+func (s *checkpointSuite) Test_Checkpoint_CopyTruncateRotation() {
+	checkpoint := s.read(nil, "/0", "/1", "/2")
+	// the file was copied, truncated and written again, way past the offset of the checkpoint
+	writeTestLogs(s.T(), path.Join(s.dir, "http.log.1"), testLogs(s.start, 0, 3), s.start.Add(time.Minute))
+	writeTestLogs(s.T(), path.Join(s.dir, "http.log"), testLogs(s.start, 3, 7), s.start.Add(2*time.Minute))
+
+	s.read(checkpoint, "/3", "/4", "/5", "/6")
+}
+
+func (s *checkpointSuite) Test_Checkpoint_Compressed() {
+	checkpoint := s.read(nil, "/0", "/1", "/2")
+	// the file was rotated and compressed, so the logs since the time of the checkpoint are read instead
+	appendTestLogs(s.T(), path.Join(s.dir, "http.log"), testLogs(s.start, 3, 4))
+	archive := &bytes.Buffer{}
+	_, err := Compress(archive, strings.NewReader(testLogs(s.start, 0, 4)), NewApacheParser(), 0)
+	s.Require().NoError(err)
+	s.Require().NoError(os.Remove(path.Join(s.dir, "http.log")))
+	writeTestLogs(s.T(), path.Join(s.dir, "http.log.1.gz"), archive.String(), s.start.Add(time.Minute))
+	writeTestLogs(s.T(), path.Join(s.dir, "http.log"), testLogs(s.start, 4, 5), s.start.Add(2*time.Minute))
+
+	checkpoint = s.read(checkpoint, "/3", "/4")
+	s.Equal("http.log", checkpoint.File)
+}
+
+func (s *checkpointSuite) Test_Checkpoint_SameSecondRotation() {
+	second := s.start.Add(10 * time.Second)
+	logs := func(paths ...string) string {
+		var logs strings.Builder
+		for _, p := range paths {
+			logs.WriteString(testLog(second, p, 200))
+		}
+		return logs.String()
+	}
+	tests := []struct {
+		name   string
+		rotate func(name string)
+	}{
+		{
+			name: "Rename",
+			rotate: func(name string) {
+				s.Require().NoError(os.Rename(name, name+".1"))
+				s.Require().NoError(os.Chtimes(name+".1", second, second))
+			},
+		},
+		{
+			// the log file of the checkpoint is gone, so the logs are read from the time of the checkpoint
+			name: "Compressed",
+			rotate: func(name string) {
+				content, err := os.ReadFile(name)
+				s.Require().NoError(err)
+				archive := &bytes.Buffer{}
+				_, err = Compress(archive, bytes.NewReader(content), NewApacheParser(), 0)
+				s.Require().NoError(err)
+				s.Require().NoError(os.Remove(name))
+				writeTestLogs(s.T(), name+".1.gz", archive.String(), second)
+			},
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			s.dir = s.T().TempDir()
+			name := path.Join(s.dir, "http.log")
+			writeTestLogs(s.T(), name, logs("/a", "/b"), second)
+			checkpoint := s.read(nil, "/a", "/b")
+			appendTestLogs(s.T(), name, logs("/c", "/d"))
+
+			test.rotate(name)
+			writeTestLogs(s.T(), name, testLog(second.Add(time.Second), "/e", 200), second.Add(time.Minute))
+
+			s.read(checkpoint, "/c", "/d", "/e")
+		})
+	}
+}
+
+func (s *checkpointSuite) Test_Checkpoint_WriteFile() {
+	checkpoint := s.read(nil, "/0", "/1", "/2")
+	name := path.Join(s.T().TempDir(), "state.json")
+
+	s.Require().NoError(checkpoint.WriteFile(name))
+	stored, err := ReadCheckpoint(name)
+
+	s.NoError(err)
+	s.Equal(checkpoint.File, stored.File)
+	s.Equal(checkpoint.Inode, stored.Inode)
+	s.Equal(checkpoint.Head, stored.Head)
+	s.Equal(checkpoint.Last, stored.Last)
+	s.Equal(checkpoint.Offset, stored.Offset)
+	s.True(checkpoint.Time.Equal(stored.Time))
+	s.NoFileExists(name + ".tmp")
+}
+
+func (s *checkpointSuite) Test_ReadCheckpoint_Error() {
+	name := path.Join(s.T().TempDir(), "state.json")
+	s.Require().NoError(os.WriteFile(name, []byte("{"), 0644))
+
+	checkpoint, err := ReadCheckpoint(name)
+
+	s.Nil(checkpoint)
+	s.EqualError(err, fmt.Sprintf("checkpoint '%s': unexpected end of JSON input", name))
+}
+
+func (s *checkpointSuite) Test_NewReader_CheckpointWithMerge() {
+	reader, err := NewReader(ReaderConfig{
+		Directory:  s.dir,
+		Merge:      true,
+		Checkpoint: &Checkpoint{},
+	})
+
+	s.Nil(reader)
+	s.EqualError(err, "checkpoint with merge: unsupported mode")
+}
+
+// read reads all the logs, or the ones following the checkpoint, checking their paths and returns the new checkpoint
+func (s *checkpointSuite) read(checkpoint *Checkpoint, expectedPaths ...string) *Checkpoint {
+	reader, err := NewReader(ReaderConfig{
+		Directory:  s.dir,
+		From:       s.start,
+		Checkpoint: checkpoint,
+	})
+	s.Require().NoError(err)
+	var paths []string
+
+	err = reader.ReadEntries(context.Background(), func(entry Entry) error {
+		paths = append(paths, entry.Path)
+		return nil
+	})
+
+	s.Require().NoError(err)
+	s.Equal(expectedPaths, paths)
+	next, err := reader.Checkpoint()
+	s.Require().NoError(err)
+	return next
+}
+
+func TestCheckpoint(t *testing.T) {
+	suite.Run(t, new(checkpointSuite))
+}
@@ -0,0 +1,18 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package logging
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileID returns the device and the inode identifying the file
+func fileID(stat os.FileInfo) (device, inode uint64) {
+	sys, ok := stat.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0
+	}
+	return uint64(sys.Dev), uint64(sys.Ino)
+}
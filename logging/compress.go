@@ -0,0 +1,96 @@
+package logging
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+var (
+	errUnsupportedCompression = errors.New("unsupported compression")
+
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// compression formats detected using the magic bytes of the log files
+const (
+	gzipCompression  = "gzip"
+	bzip2Compression = "bzip2"
+	zstdCompression  = "zstd"
+)
+
+// decompress detects whether a file is compressed using its magic bytes
+// and returns a reader of the decompressed content, gzip and bzip2 are supported.
+// compressed == false -> the file is a plain text file and should be read as it is
+func decompress(file *os.File) (r io.Reader, compressed bool, err error) {
+	format, err := compression(file)
+	if err != nil {
+		return nil, false, err
+	}
+
+	switch format {
+	case gzipCompression:
+		_, err := file.Seek(0, io.SeekStart)
+		if err != nil {
+			return nil, true, err
+		}
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, true, err
+		}
+		return gz, true, nil
+	case bzip2Compression:
+		_, err := file.Seek(0, io.SeekStart)
+		if err != nil {
+			return nil, true, err
+		}
+		return bzip2.NewReader(file), true, nil
+	case zstdCompression:
+		return nil, true, fmt.Errorf("file '%s': zstd: %w", file.Name(), errUnsupportedCompression)
+	}
+
+	return file, false, nil
+}
+
+// compression detects the compression format of a file using its magic bytes, "" is returned for plain text files
+func compression(file *os.File) (string, error) {
+	magic := make([]byte, 4)
+	n, err := file.ReadAt(magic, 0)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	magic = magic[:n]
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		return gzipCompression, nil
+	case bytes.HasPrefix(magic, bzip2Magic):
+		return bzip2Compression, nil
+	case bytes.HasPrefix(magic, zstdMagic):
+		return zstdCompression, nil
+	}
+	return "", nil
+}
+
+// fileCompression detects the compression format of the file at the given path, see compression
+func fileCompression(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = file.Close() }()
+
+	return compression(file)
+}
+
+// isCompressed checks whether the file at the given path is compressed using its magic bytes
+func isCompressed(filePath string) (bool, error) {
+	format, err := fileCompression(filePath)
+	return format != "", err
+}
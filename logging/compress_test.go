@@ -0,0 +1,129 @@
+package logging
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"log"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type compressSuite struct {
+	suite.Suite
+}
+
+func (s *compressSuite) Test_decompress_Success() {
+	// "hello\n" compressed with: bzip2 -9
+	bzip2Hello := []byte{
+		0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0xc1, 0xc0, 0x80, 0xe2, 0x00, 0x00,
+		0x01, 0x41, 0x00, 0x00, 0x10, 0x02, 0x44, 0xa0, 0x00, 0x30, 0xcd, 0x00, 0xc3, 0x46, 0x29, 0x97,
+		0x17, 0x72, 0x45, 0x38, 0x50, 0x90, 0xc1, 0xc0, 0x80, 0xe2,
+	}
+	tests := []struct {
+		name               string
+		content            func(w io.Writer)
+		expectedCompressed bool
+	}{
+		{
+			name: "Plain",
+			content: func(w io.Writer) {
+				_, err := w.Write([]byte("hello\n"))
+				s.Require().NoError(err)
+			},
+			expectedCompressed: false,
+		},
+		{
+			name: "Gzip",
+			content: func(w io.Writer) {
+				gz := gzip.NewWriter(w)
+				_, err := gz.Write([]byte("hello\n"))
+				s.Require().NoError(err)
+				s.Require().NoError(gz.Close())
+			},
+			expectedCompressed: true,
+		},
+		{
+			name: "Bzip2",
+			content: func(w io.Writer) {
+				_, err := w.Write(bzip2Hello)
+				s.Require().NoError(err)
+			},
+			expectedCompressed: true,
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			f, err := os.CreateTemp(s.T().TempDir(), "*-http.log")
+			s.Require().NoError(err)
+			defer func() { s.Require().NoError(f.Close()) }()
+			test.content(f)
+			_, err = f.Seek(0, io.SeekStart)
+			s.Require().NoError(err)
+
+			r, compressed, err := decompress(f)
+			s.Require().NoError(err)
+			content, err := io.ReadAll(r)
+
+			s.NoError(err)
+			s.Equal(test.expectedCompressed, compressed)
+			s.Equal("hello\n", string(content))
+		})
+	}
+}
+
+func (s *compressSuite) Test_decompress_Empty() {
+	f, err := os.CreateTemp(s.T().TempDir(), "*-http.log")
+	s.Require().NoError(err)
+	defer func() { s.Require().NoError(f.Close()) }()
+
+	r, compressed, err := decompress(f)
+
+	s.NoError(err)
+	s.False(compressed)
+	s.Equal(f, r)
+}
+
+func (s *compressSuite) Test_decompress_Error() {
+	f, err := os.CreateTemp(s.T().TempDir(), "http.log.zst")
+	s.Require().NoError(err)
+	defer func() { s.Require().NoError(f.Close()) }()
+	_, err = f.Write(append(zstdMagic, 0x00, 0x00))
+	s.Require().NoError(err)
+
+	r, compressed, err := decompress(f)
+
+	s.EqualError(err, "file '"+f.Name()+"': zstd: unsupported compression")
+	s.True(compressed)
+	s.Nil(r)
+}
+
+func (s *compressSuite) Test_Read_UnsupportedCompression() {
+	dir := s.T().TempDir()
+	logs := testLogs(testStart, 0, 3)
+	writeTestLogs(s.T(), path.Join(dir, "http.log.1.zst"), string(append(zstdMagic, 0x00, 0x00)), testStart)
+	writeTestLogs(s.T(), path.Join(dir, "http.log"), logs, testStart.Add(time.Minute))
+	warnings := &bytes.Buffer{}
+	reader, err := NewReader(ReaderConfig{
+		Directory: dir,
+		From:      testStart,
+		Logger:    log.New(warnings, "", 0),
+	})
+	s.Require().NoError(err)
+	buf := &bytes.Buffer{}
+
+	err = reader.Read(context.Background(), buf)
+
+	s.NoError(err)
+	s.Equal(logs, buf.String())
+	s.Equal("warning: file 'http.log.1.zst': zstd: unsupported compression, skipped\n", warnings.String())
+}
+
+func TestCompress(t *testing.T) {
+	suite.Run(t, new(compressSuite))
+}
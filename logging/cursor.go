@@ -0,0 +1,137 @@
+package logging
+
+import (
+	"context"
+)
+
+// Line represents a log line alongside the log file it was read from
+type Line struct {
+	// Text is the log line without its line ending
+	Text string
+	// File is the name of the log file inside the logs directory
+	File string
+	// Offset is the byte offset of the line inside the log file, -1 for compressed log files
+	Offset int64
+
+	// parsed is set when the line was already parsed by a worker, see ReaderConfig.Workers
+	parsed *parsedLine
+}
+
+// cursorItem represents a log line read by a cursor, alongside its parsed entry
+type cursorItem struct {
+	line  Line
+	entry Entry
+}
+
+// Cursor iterates over the logs read by a Reader, pulling them one at a time instead of having them pushed to a writer.
+// The reading runs in the background one log ahead of the cursor, it stops once there are no logs left,
+// the context is done or the cursor is closed. A cursor must always be closed.
+//
+//	cursor := reader.Entries(ctx)
+//	defer cursor.Close()
+//	for cursor.Next() {
+//		entry := cursor.Entry()
+//		...
+//	}
+//	if err := cursor.Err(); err != nil {
+//		...
+//	}
+type Cursor struct {
+	items  chan cursorItem
+	cancel context.CancelFunc
+	item   cursorItem
+	// err is set by the reading goroutine before items is closed, it's read only after items is closed
+	err  error
+	done bool
+}
+
+// Lines returns a cursor over the log lines that are within the time window and match the filter,
+// the same ones Read writes. The lines are only parsed when there is a filter, Cursor.Entry is empty otherwise.
+// A reader must not be read by another cursor or Read call till the cursor is closed
+func (r *Reader) Lines(ctx context.Context) *Cursor {
+	return r.cursor(ctx, false)
+}
+
+// Entries returns a cursor over the parsed log lines that are within the time window and match the filter,
+// the same ones ReadEntries calls fn with.
+// A reader must not be read by another cursor or Read call till the cursor is closed
+func (r *Reader) Entries(ctx context.Context) *Cursor {
+	return r.cursor(ctx, true)
+}
+
+func (r *Reader) cursor(ctx context.Context, parse bool) *Cursor {
+	ctx, cancel := context.WithCancel(ctx)
+	c := &Cursor{
+		items:  make(chan cursorItem),
+		cancel: cancel,
+	}
+
+	go func() {
+		defer close(c.items)
+		c.err = r.run(ctx, parse || r.cfg.Filter != nil, func(line Line) error {
+			item := cursorItem{line: line}
+			item.line.parsed = nil
+			if parse || r.cfg.Filter != nil {
+				entry, err := r.entry(line)
+				if err != nil {
+					return err
+				}
+				item.entry = entry
+			}
+
+			select {
+			case c.items <- item:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+	return c
+}
+
+// Next advances the cursor to the next log, false is returned once there are no logs left or the reading failed
+func (c *Cursor) Next() bool {
+	if c.done {
+		return false
+	}
+
+	item, ok := <-c.items
+	if !ok {
+		c.done = true
+		c.item = cursorItem{}
+		return false
+	}
+	c.item = item
+	return true
+}
+
+// Line returns the current log line
+func (c *Cursor) Line() Line {
+	return c.item.line
+}
+
+// Entry returns the current parsed log line
+func (c *Cursor) Entry() Entry {
+	return c.item.entry
+}
+
+// Err returns the error that stopped the reading, if any, once Next returned false.
+// Same as Read, a done context is not an error
+func (c *Cursor) Err() error {
+	if !c.done {
+		return nil
+	}
+	return c.err
+}
+
+// Close stops the reading and waits for it to release the log files, returning the error that stopped it, if any.
+// Close can be called more than once
+func (c *Cursor) Close() error {
+	c.cancel()
+	for range c.items {
+	}
+	c.done = true
+	c.item = cursorItem{}
+	return c.err
+}
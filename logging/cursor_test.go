@@ -0,0 +1,154 @@
+package logging
+
+import (
+	"context"
+	"time"
+)
+
+func (s *readerSuite) Test_Entries_Success() {
+	reader, err := NewReader(ReaderConfig{
+		Directory: testDataDir,
+		Since:     90 * time.Second,
+	})
+	s.Require().NoError(err)
+	reader.nowFunc = s.nowFunc
+	var times []string
+	var lines []Line
+
+	cursor := reader.Entries(context.Background())
+	for cursor.Next() {
+		s.Equal("/api/endpoint", cursor.Entry().Path)
+		times = append(times, cursor.Entry().Time.Format(dateTimeFormat))
+		line := cursor.Line()
+		line.Text = ""
+		lines = append(lines, line)
+	}
+
+	s.NoError(cursor.Err())
+	s.NoError(cursor.Close())
+	s.Equal([]string{
+		"03/Mar/2022:02:43:40 +0000",
+		"03/Mar/2022:02:44:00 +0000",
+		"03/Mar/2022:02:45:00 +0000",
+		"03/Mar/2022:02:45:20 +0000",
+		"03/Mar/2022:02:45:40 +0000",
+	}, times)
+	// each log line is 98 bytes long
+	s.Equal([]Line{
+		{File: "http-2.log", Offset: 98},
+		{File: "http-2.log", Offset: 196},
+		{File: "http-3.log", Offset: 0},
+		{File: "http-3.log", Offset: 98},
+		{File: "http-3.log", Offset: 196},
+	}, lines)
+}
+
+func (s *readerSuite) Test_Lines_Merge() {
+	reader, err := NewReader(ReaderConfig{
+		Directory: testDataDir,
+		Since:     90 * time.Second,
+		Merge:     true,
+	})
+	s.Require().NoError(err)
+	reader.nowFunc = s.nowFunc
+	var lines []Line
+
+	cursor := reader.Lines(context.Background())
+	for cursor.Next() {
+		s.Empty(cursor.Entry().Path)
+		lines = append(lines, cursor.Line())
+	}
+
+	s.NoError(cursor.Close())
+	s.Len(lines, 5)
+	s.Equal("http-2.log", lines[0].File)
+	s.Equal(int64(98), lines[0].Offset)
+	s.Contains(lines[0].Text, "[03/Mar/2022:02:43:40 +0000]")
+}
+
+func (s *readerSuite) Test_Entries_Malformed() {
+	dir := s.T().TempDir()
+	s.createLogFile(dir, "http.log", `127.0.0.1 user-identifier frank [03/Mar/2022:02:44:00 +0000] "GET /a HTTP/1.0" 500 123
+127.0.0.1 user-identifier frank [03/Mar/2022:02:44:10 +0000] "GET /b HTTP/1.0" 500 123
+127.0.0.1 user-identifier frank [03/Mar/2022:02:44:20 +0000] "GET /c HTTP/1.0" 500 123
+junk
+127.0.0.1 user-identifier frank [03/Mar/2022:02:44:30 +0000] "GET /d HTTP/1.0" 500 123
+`)
+	reader, err := NewReader(ReaderConfig{Directory: dir, Since: 2 * time.Minute})
+	s.Require().NoError(err)
+	reader.nowFunc = s.nowFunc
+	var paths []string
+
+	cursor := reader.Entries(context.Background())
+	for cursor.Next() {
+		paths = append(paths, cursor.Entry().Path)
+	}
+
+	s.Equal([]string{"/a", "/b", "/c"}, paths)
+	s.Error(cursor.Err())
+	s.Equal(cursor.Err(), cursor.Close())
+}
+
+func (s *readerSuite) Test_Entries_Close() {
+	tests := []struct {
+		name   string
+		cfg    ReaderConfig
+		stopAt int
+	}{
+		{
+			name:   "Before Reading",
+			cfg:    ReaderConfig{Directory: testDataDir, Since: 90 * time.Second},
+			stopAt: 0,
+		},
+		{
+			name:   "While Streaming",
+			cfg:    ReaderConfig{Directory: testDataDir, Since: 90 * time.Second},
+			stopAt: 3,
+		},
+		{
+			name:   "While Merging",
+			cfg:    ReaderConfig{Directory: testDataDir, Since: 90 * time.Second, Merge: true},
+			stopAt: 2,
+		},
+		{
+			name:   "While Following",
+			cfg:    ReaderConfig{Directory: testDataDir, Since: 90 * time.Second, Follow: true, FollowInterval: time.Millisecond},
+			stopAt: 5,
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			s.assertNoLeaks(func() {
+				reader, err := NewReader(test.cfg)
+				s.Require().NoError(err)
+				reader.nowFunc = s.nowFunc
+				cursor := reader.Entries(context.Background())
+
+				for i := 0; i < test.stopAt; i++ {
+					s.Require().True(cursor.Next())
+				}
+
+				s.NoError(cursor.Close())
+				s.False(cursor.Next())
+				s.NoError(cursor.Close())
+			})
+		})
+	}
+}
+
+func (s *readerSuite) Test_Entries_Canceled() {
+	reader, err := NewReader(ReaderConfig{Directory: testDataDir, Since: 90 * time.Second})
+	s.Require().NoError(err)
+	reader.nowFunc = s.nowFunc
+	ctx, cancel := context.WithCancel(context.Background())
+	cursor := reader.Entries(ctx)
+	defer func() { _ = cursor.Close() }()
+
+	s.True(cursor.Next())
+	cancel()
+	for cursor.Next() {
+	}
+
+	// same as Read, a canceled read is not a failure
+	s.NoError(cursor.Err())
+}
@@ -0,0 +1,50 @@
+package logging
+
+import (
+	"time"
+)
+
+const (
+	dateTimeGroupName   = "datetime"
+	remoteHostGroupName = "host"
+	identGroupName      = "ident"
+	userGroupName       = "user"
+	methodGroupName     = "method"
+	pathGroupName       = "path"
+	protocolGroupName   = "protocol"
+	statusGroupName     = "status"
+	bytesGroupName      = "bytes"
+	refererGroupName    = "referer"
+	userAgentGroupName  = "agent"
+)
+
+// Entry represents a parsed log line
+type Entry struct {
+	RemoteHost string    `json:"remote_host"`
+	Ident      string    `json:"ident"`
+	User       string    `json:"user"`
+	Time       time.Time `json:"time"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Protocol   string    `json:"protocol"`
+	Status     int       `json:"status"`
+	Bytes      int64     `json:"bytes"`
+	Referer    string    `json:"referer"`
+	UserAgent  string    `json:"user_agent"`
+	// Duration is the time taken to serve the request, when the log format has it (nanoseconds in JSON)
+	Duration time.Duration `json:"duration"`
+	// Fields stores any other named value the log format has, i.e. request headers like X-Request-Id
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// apacheParser is the line parser ParseEntry uses, parsers are safe for concurrent use
+var apacheParser = NewApacheParser()
+
+// ParseEntry parses a given apache common or combined log line into an Entry.
+// Missing values like "-" for status or bytes are left as zero values.
+// It's a shorthand for NewApacheParser().ParseEntry, use a LineParser for the other log formats
+// example of apache combined log line:
+// 127.0.0.1 - frank [04/Mar/2022:05:30:00 +0000] "GET /api/endpoint HTTP/1.0" 200 123 "http://example.com" "curl/7.79.1"
+func ParseEntry(l string) (Entry, error) {
+	return apacheParser.ParseEntry(l)
+}
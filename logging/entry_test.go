@@ -0,0 +1,122 @@
+package logging
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type entrySuite struct {
+	suite.Suite
+}
+
+func (s *entrySuite) Test_ParseEntry_Success() {
+	logTime, err := time.Parse(dateTimeFormat, "04/Mar/2022:05:30:00 +0000")
+	s.Require().NoError(err)
+	tests := []struct {
+		name          string
+		log           string
+		expectedEntry Entry
+	}{
+		{
+			name: "Common Log Format",
+			log:  `127.0.0.1 user-identifier frank [04/Mar/2022:05:30:00 +0000] "GET /api/endpoint HTTP/1.0" 500 123`,
+			expectedEntry: Entry{
+				RemoteHost: "127.0.0.1",
+				Ident:      "user-identifier",
+				User:       "frank",
+				Time:       logTime,
+				Method:     "GET",
+				Path:       "/api/endpoint",
+				Protocol:   "HTTP/1.0",
+				Status:     500,
+				Bytes:      123,
+			},
+		},
+		{
+			name: "Combined Log Format",
+			log:  `10.0.0.7 - - [04/Mar/2022:05:30:00 +0000] "POST /api/users?id=1 HTTP/1.1" 201 2326 "http://example.com/start.html" "Mozilla/5.0 (X11; Linux x86_64)"`,
+			expectedEntry: Entry{
+				RemoteHost: "10.0.0.7",
+				Ident:      "-",
+				User:       "-",
+				Time:       logTime,
+				Method:     "POST",
+				Path:       "/api/users?id=1",
+				Protocol:   "HTTP/1.1",
+				Status:     201,
+				Bytes:      2326,
+				Referer:    "http://example.com/start.html",
+				UserAgent:  "Mozilla/5.0 (X11; Linux x86_64)",
+			},
+		},
+		{
+			name: "Missing Values",
+			log:  `127.0.0.1 - - [04/Mar/2022:05:30:00 +0000] "-" - -`,
+			expectedEntry: Entry{
+				RemoteHost: "127.0.0.1",
+				Ident:      "-",
+				User:       "-",
+				Time:       logTime,
+				Method:     "-",
+			},
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			entry, err := ParseEntry(test.log)
+
+			s.NoError(err)
+			s.Equal(test.expectedEntry, entry)
+		})
+	}
+}
+
+func (s *entrySuite) Test_ParseEntry_Error() {
+	tests := []struct {
+		name        string
+		log         string
+		expectedErr string
+	}{
+		{
+			name:        "Invalid LogLine",
+			log:         "this log line is not valid",
+			expectedErr: "line 'this log line is not valid': invalid log format",
+		},
+		{
+			name:        "Invalid DateFormat",
+			log:         `127.0.0.1 user-identifier frank [36/Mar/2022:05:30:00 +0000] "GET /api/endpoint HTTP/1.0" 500 123`,
+			expectedErr: `parsing time "36/Mar/2022:05:30:00 +0000": day out of range`,
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			entry, err := ParseEntry(test.log)
+
+			s.EqualError(err, test.expectedErr)
+			s.Equal(Entry{}, entry)
+		})
+	}
+}
+
+func (s *entrySuite) Test_ParseEntry_ApacheParser() {
+	logs := []string{
+		`127.0.0.1 user-identifier frank [04/Mar/2022:05:30:00 +0000] "GET /api/endpoint HTTP/1.0" 500 123`,
+		`10.0.0.7 - - [04/Mar/2022:05:30:00 +0000] "POST /api/users HTTP/1.1" 201 2326 "http://example.com" "curl/7.79.1"`,
+		"this log line is not valid",
+	}
+	parser := NewApacheParser()
+	for _, log := range logs {
+		expectedEntry, expectedErr := parser.ParseEntry(log)
+
+		entry, err := ParseEntry(log)
+
+		s.Equal(expectedErr, err)
+		s.Equal(expectedEntry, entry)
+	}
+}
+
+func TestEntry(t *testing.T) {
+	suite.Run(t, new(entrySuite))
+}
@@ -1,34 +1,21 @@
 package logging
 
 import (
-	"bufio"
-	"errors"
-	"fmt"
+	"context"
 	"io"
 	"math"
 	"os"
-	"regexp"
 	"strings"
 	"time"
 )
 
-const (
-	dateTimeGroupName = "datetime"
-	dateTimeFormat    = "02/Jan/2006:15:04:05 -0700"
-)
-
-var errInvalidLogFormat = errors.New("invalid log format")
-
-// NewFile wraps an os.File creating a special apache common log format regex
+// NewFile wraps an os.File using the given line parser to understand the log format
 // and adding useful helper functions such as seekLine and search for easier working with log files
-func NewFile(file *os.File) *File {
-	logFormat := fmt.Sprintf(
-		`^(\S+) (\S+) (\S+) \[(?P<%s>[\w:/]+\s[+\-]\d{4})\] "(\S+)\s?(\S+)?\s?(\S+)?" (\d{3}|-) (\d+|-)\s?"?([^"]*)"?\s?"?([^"]*)?"?$`,
-		dateTimeGroupName,
-	)
+func NewFile(file *os.File, parser LineParser) *File {
 	return &File{
-		File:  file,
-		regEx: regexp.MustCompile(logFormat),
+		File:        file,
+		parser:      parser,
+		maxLineSize: DefaultMaxLineSize,
 	}
 }
 
@@ -36,97 +23,203 @@ func NewFile(file *os.File) *File {
 // providing additional constructs and helpers for working with log files
 type File struct {
 	*os.File
-	regEx *regexp.Regexp
+	parser LineParser
+	// maxLineSize is the maximum size of a log line, the binary search fails on longer lines
+	// unless skipLongLines is set, the same way skipMalformed skips the lines that can't be parsed
+	maxLineSize   int
+	skipLongLines bool
+	skipMalformed bool
+	// timeIndex is the sidecar index of the log file, loaded and validated by the first search, nil when there is none
+	timeIndex       *TimeIndex
+	timeIndexLoaded bool
 }
 
 // IndexTime applies a binary search on a log file looking for
-// the offset of the log that is withing lookup time (that took place within the last T time).
+// the offset of the first log that took place at or after the lookup time (within the last T time).
 // offset >= 0 -> means an actual log line to begin reading logs at was found
 // offset == -1 -> all the logs inside the log file are older than the lookup time T
+// The sidecar index built by IndexFile is consulted first, when it's still valid
 func (file *File) IndexTime(lookupTime time.Time) (int64, error) {
-	var top, bottom, pos, prevPos, offset, prevOffset int64
-	scanLines := func(data []byte, atEOF bool) (advance int, token []byte, err error) {
-		advance, token, err = bufio.ScanLines(data, atEOF)
-		prevPos = pos
-		pos += int64(advance)
-		return
+	return file.indexTime(context.Background(), lookupTime)
+}
+
+// indexTime is IndexTime stopping the search as soon as the context is done
+func (file *File) indexTime(ctx context.Context, lookupTime time.Time) (int64, error) {
+	offset, err := file.search(ctx, func(logTime time.Time) bool {
+		return !logTime.Before(lookupTime)
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		return -1, err
+	}
+	if offset >= stat.Size() {
+		return -1, nil
+	}
+	return offset, nil
+}
+
+// IndexTimeEnd applies a binary search on a log file looking for
+// the offset right after the last log that took place at or before the lookup time (the end of a time window).
+// offset == size of the file -> all the logs inside the log file are within the lookup time
+// offset == 0 -> all the logs inside the log file are newer than the lookup time
+func (file *File) IndexTimeEnd(lookupTime time.Time) (int64, error) {
+	return file.indexTimeEnd(context.Background(), lookupTime)
+}
+
+// indexTimeEnd is IndexTimeEnd stopping the search as soon as the context is done
+func (file *File) indexTimeEnd(ctx context.Context, lookupTime time.Time) (int64, error) {
+	return file.search(ctx, func(logTime time.Time) bool {
+		return logTime.After(lookupTime)
+	})
+}
+
+// TimeRange returns the time of the first and the last log inside the log file
+// reading only the first line and the last line, found by seeking back from the end of the file.
+// first.IsZero() -> the log file has no logs
+// last.IsZero() -> the last log could not be found, i.e. the file ends with blank lines
+func (file *File) TimeRange() (first, last time.Time, err error) {
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	first, err = firstLogTime(file.lines(context.Background(), 0), file.parser, file.skipMalformed)
+	if err != nil || first.IsZero() {
+		return time.Time{}, time.Time{}, err
+	}
+
+	// step back over the trailing new line, so the cursor is inside the last line
+	_, err = file.Seek(-1, io.SeekEnd)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	offset, err := file.seekLine(0, io.SeekCurrent)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	lines := file.lines(context.Background(), offset)
+	var line string
+	for {
+		next, err := lines.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		if strings.TrimSpace(next) != "" {
+			line = next
+		}
+	}
+	if line == "" {
+		return first, time.Time{}, nil
+	}
+
+	last, err = file.parser.ParseTime(line)
+	if err != nil && file.skipMalformed {
+		return first, time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, time.Time{}, err
 	}
+	return first, last, nil
+}
 
-	stat, err := os.Stat(file.Name())
+// search applies a binary search on a log file looking for the offset of the first log line
+// for which the after function returns true, or the size of the file if there is no such line.
+// after must be monotonic: once it holds for a log line it has to hold for all the lines below it
+func (file *File) search(ctx context.Context, after func(logTime time.Time) bool) (int64, error) {
+	stat, err := file.Stat()
 	if err != nil {
 		return -1, err
 	}
-	bottom = stat.Size()
-	var prevLogTime time.Time
-	for top <= bottom {
-		// define the middle relative to the top and bottom positions
+
+	// top is always the beginning of a line and bottom is either
+	// the beginning of a line or the end of the file.
+	// The sidecar index, if any, narrows them down to the logs between two index entries
+	top, bottom := int64(0), stat.Size()
+	if index := file.index(); index != nil {
+		top, bottom = index.bounds(after, stat.Size())
+	}
+	for top < bottom {
+		if err := ctx.Err(); err != nil {
+			return -1, err
+		}
+
 		middle := top + (bottom-top)/2
-		// seek the file at the middle
 		_, err := file.Seek(middle, io.SeekStart)
 		if err != nil {
 			return -1, err
 		}
 		// reposition the middle to the beginning of the current line
-		offset, err = file.seekLine(0, io.SeekCurrent)
+		offset, err := file.seekLine(0, io.SeekCurrent)
 		if err != nil {
 			return -1, err
 		}
 
-		// scan 1 line and parse 1 log line
-		scanner := bufio.NewScanner(file)
-		scanner.Split(scanLines)
-		scanner.Scan()
-		line := scanner.Text()
-		if strings.TrimSpace(line) == "" {
-			// we'll consider empty line an EOF
-			break
-		}
-
-		logTime, err := file.parseLogTime(line)
+		logTime, next, ok, err := file.probe(ctx, offset, bottom)
 		if err != nil {
 			return -1, err
 		}
+		// we'll consider empty line an EOF
+		if !ok {
+			bottom = offset
+			continue
+		}
 
-		if lookupTime.Sub(logTime) > 0 {
-			// the starting log is way down (relative to the middle)
-			// move down the top
-			top = offset + (pos - prevPos)
-		} else if prevLogTime.Sub(logTime) < 0 {
-			// the starting log is way up (relative to the middle)
-			// move up the bottom
-			bottom = offset - (pos - prevPos)
-		} else if lookupTime.Sub(prevLogTime) < 0 && offset != top {
-			if lookupTime.Minute() == logTime.Minute() {
-				return offset - (pos - prevPos), nil
-			}
-			return top, nil
+		if after(logTime) {
+			// the log we're looking for is either this one or somewhere above it
+			bottom = offset
+		} else {
+			// the log we're looking for is somewhere below this one
+			top = next
 		}
+	}
 
-		if offset == top {
-			if lookupTime.Minute() == logTime.Minute() || top == 0 {
-				return top, nil
-			}
-			return offset - (pos - prevPos), nil
+	return top, nil
+}
+
+// probe returns the time of the first log found at or after the offset, as long as it begins before the end offset,
+// alongside the offset right after it. Malformed and too long lines are either skipped, probing the lines below them,
+// or fail the search, depending on the policies.
+// ok == false -> there is no log before the end offset or a blank line was found first
+func (file *File) probe(ctx context.Context, offset, end int64) (logTime time.Time, next int64, ok bool, err error) {
+	lines := file.lines(ctx, offset)
+	for {
+		line, err := lines.next()
+		if err == io.EOF {
+			return time.Time{}, 0, false, nil
 		}
-		if offset == bottom {
-			if lookupTime.Minute() > logTime.Minute() {
-				return top, nil
-			}
-			return bottom, nil
+		if err != nil {
+			return time.Time{}, 0, false, err
 		}
-		if top == bottom && top == stat.Size() {
-			return -1, nil
+		if lines.start >= end || strings.TrimSpace(line) == "" {
+			return time.Time{}, 0, false, nil
 		}
 
-		prevLogTime = logTime
-		prevOffset = offset
+		logTime, err := file.parser.ParseTime(line)
+		if err != nil && file.skipMalformed {
+			continue
+		}
+		if err != nil {
+			return time.Time{}, 0, false, err
+		}
+		return logTime, lines.offset, true, nil
 	}
+}
 
-	if lookupTime.Unix() == prevLogTime.Unix() {
-		return prevOffset, nil
+// lines creates a line reader starting at the given offset of the log file, skipping too long lines if configured to
+func (file *File) lines(ctx context.Context, offset int64) *lineReader {
+	var tooLong func(err error) error
+	if file.skipLongLines {
+		tooLong = func(error) error {
+			return nil
+		}
 	}
-
-	return -1, nil
+	return newLineReader(ctx, file, offset, file.maxLineSize, tooLong)
 }
 
 // seekLine resets the cursor for N lines relative to whence, back to the beginning (seek back)
@@ -199,30 +292,24 @@ func (file *File) seekLine(lines int64, whence int) (int64, error) {
 	return pos, err
 }
 
-// parseLogTime parses a given apache common log line and attempts to convert it into time.Time
-// example of apache common log line:
-// 127.0.0.1 user-identifier frank [04/Mar/2022:05:30:00 +0000] "GET /api/endpoint HTTP/1.0" 500 123
-func (file *File) parseLogTime(l string) (time.Time, error) {
-	matches := file.regEx.FindStringSubmatch(l)
-	if len(matches) == 0 {
-		return time.Time{}, fmt.Errorf("line '%s': %w", l, errInvalidLogFormat)
-	}
-
-	var dateTime string
-	for i, name := range file.regEx.SubexpNames() {
-		if name == dateTimeGroupName {
-			dateTime = matches[i]
-			break
+// firstLogTime returns the time of the first log read from the given lines, skipping blank lines
+// and malformed lines as well if skipMalformed is set. A zero time is returned when there are no logs at all
+func firstLogTime(lines *lineReader, parser LineParser, skipMalformed bool) (time.Time, error) {
+	for {
+		line, err := lines.next()
+		if err == io.EOF {
+			return time.Time{}, nil
 		}
+		if err != nil {
+			return time.Time{}, err
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		logTime, err := parser.ParseTime(line)
+		if err != nil && skipMalformed {
+			continue
+		}
+		return logTime, err
 	}
-	if dateTime == "" {
-		return time.Time{}, fmt.Errorf("invalid date: %w", errInvalidLogFormat)
-	}
-
-	t, err := time.Parse(dateTimeFormat, dateTime)
-	if err != nil {
-		return time.Time{}, err
-	}
-
-	return t, nil
 }
@@ -35,11 +35,11 @@ func (s *fileSuite) Test_NewFile() {
 	defer func() { s.Require().NoError(f.Close()) }()
 	s.Require().NoError(err)
 
-	file := NewFile(f)
+	file := NewFile(f, NewApacheParser())
 
 	s.NotNil(file)
 	s.NotNil(file.File)
-	s.NotNil(file.regEx)
+	s.NotNil(file.parser)
 }
 
 func (s *fileSuite) Test_IndexTime_Success() {
@@ -67,7 +67,7 @@ func (s *fileSuite) Test_IndexTime_Success() {
 	s.Require().NoError(err)
 	f := s.createLogs(logs)
 	defer func() { s.Require().NoError(f.Close()) }()
-	file := NewFile(f)
+	file := NewFile(f, NewApacheParser())
 	s.NotNil(file)
 	tests := []struct {
 		name           string
@@ -99,6 +99,24 @@ func (s *fileSuite) Test_IndexTime_Success() {
 			expectedOffset: 1470,
 			expectedLog:    `127.0.0.1 user-identifier frank [07/Mar/2022:02:42:02 +0000] "GET /api/endpoint HTTP/1.0" 500 123`,
 		},
+		{
+			name:           "Last 28 Seconds Exact Log Time",
+			timeLookup:     now.Add(-28 * time.Second),
+			expectedOffset: 1764,
+			expectedLog:    `127.0.0.1 user-identifier frank [07/Mar/2022:02:42:32 +0000] "GET /api/endpoint HTTP/1.0" 500 123`,
+		},
+		{
+			name:           "Last 90 Seconds",
+			timeLookup:     now.Add(-90 * time.Second),
+			expectedOffset: 1176,
+			expectedLog:    `127.0.0.1 user-identifier frank [07/Mar/2022:02:41:32 +0000] "GET /api/endpoint HTTP/1.0" 500 123`,
+		},
+		{
+			name:           "Sub Second Boundary",
+			timeLookup:     now.Add(-28*time.Second + time.Millisecond),
+			expectedOffset: 1862,
+			expectedLog:    `127.0.0.1 user-identifier frank [07/Mar/2022:02:42:42 +0000] "GET /api/endpoint HTTP/1.0" 500 123`,
+		},
 		{
 			name:           "Last 2 Days From Beginning",
 			timeLookup:     now.Add(-2 * time.Hour * 24),
@@ -124,10 +142,87 @@ func (s *fileSuite) Test_IndexTime_Success() {
 	}
 }
 
+func (s *fileSuite) Test_IndexTimeEnd_Success() {
+	logs := `127.0.0.1 user-identifier frank [07/Mar/2022:02:39:32 +0000] "GET /api/endpoint HTTP/1.0" 500 123
+127.0.0.1 user-identifier frank [07/Mar/2022:02:39:42 +0000] "GET /api/endpoint HTTP/1.0" 500 123
+127.0.0.1 user-identifier frank [07/Mar/2022:02:39:52 +0000] "GET /api/endpoint HTTP/1.0" 500 123
+127.0.0.1 user-identifier frank [07/Mar/2022:02:40:02 +0000] "GET /api/endpoint HTTP/1.0" 500 123
+127.0.0.1 user-identifier frank [07/Mar/2022:02:40:12 +0000] "GET /api/endpoint HTTP/1.0" 500 123
+127.0.0.1 user-identifier frank [07/Mar/2022:02:40:12 +0000] "GET /api/endpoint HTTP/1.0" 500 123
+127.0.0.1 user-identifier frank [07/Mar/2022:02:40:22 +0000] "GET /api/endpoint HTTP/1.0" 500 123
+`
+	f := s.createLogs(logs)
+	defer func() { s.Require().NoError(f.Close()) }()
+	file := NewFile(f, NewApacheParser())
+	s.NotNil(file)
+	tests := []struct {
+		name           string
+		timeLookup     string
+		expectedOffset int64
+		expectedLog    string
+	}{
+		{
+			name:           "Before First Log",
+			timeLookup:     "07/Mar/2022:02:39:00 +0000",
+			expectedOffset: 0,
+			expectedLog:    `127.0.0.1 user-identifier frank [07/Mar/2022:02:39:32 +0000] "GET /api/endpoint HTTP/1.0" 500 123`,
+		},
+		{
+			name:           "Exact Log Time",
+			timeLookup:     "07/Mar/2022:02:39:42 +0000",
+			expectedOffset: 196,
+			expectedLog:    `127.0.0.1 user-identifier frank [07/Mar/2022:02:39:52 +0000] "GET /api/endpoint HTTP/1.0" 500 123`,
+		},
+		{
+			name:           "Between Logs",
+			timeLookup:     "07/Mar/2022:02:39:55 +0000",
+			expectedOffset: 294,
+			expectedLog:    `127.0.0.1 user-identifier frank [07/Mar/2022:02:40:02 +0000] "GET /api/endpoint HTTP/1.0" 500 123`,
+		},
+		{
+			name:           "Duplicate Log Times",
+			timeLookup:     "07/Mar/2022:02:40:12 +0000",
+			expectedOffset: 588,
+			expectedLog:    `127.0.0.1 user-identifier frank [07/Mar/2022:02:40:22 +0000] "GET /api/endpoint HTTP/1.0" 500 123`,
+		},
+		{
+			name:           "After Last Log",
+			timeLookup:     "07/Mar/2022:02:41:00 +0000",
+			expectedOffset: int64(len(logs)),
+			expectedLog:    ``,
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			lookupTime, err := time.Parse(dateTimeFormat, test.timeLookup)
+			s.Require().NoError(err)
+
+			offset, err := file.IndexTimeEnd(lookupTime)
+			log := s.readLogAt(f, offset)
+
+			s.NoError(err)
+			s.Equal(test.expectedOffset, offset)
+			s.Equal(test.expectedLog, log)
+		})
+	}
+}
+
+func (s *fileSuite) Test_IndexTimeEnd_Error() {
+	f := s.createLogs("some invalid log line\n")
+	defer func() { s.Require().NoError(f.Close()) }()
+	file := NewFile(f, NewApacheParser())
+	s.NotNil(file)
+
+	offset, err := file.IndexTimeEnd(time.Now().UTC())
+
+	s.EqualError(err, "line 'some invalid log line': invalid log format")
+	s.Equal(int64(-1), offset)
+}
+
 func (s *fileSuite) Test_IndexTime_Error() {
 	f := s.createLogs("some invalid log line\n")
 	defer func() { s.Require().NoError(f.Close()) }()
-	file := NewFile(f)
+	file := NewFile(f, NewApacheParser())
 	s.NotNil(file)
 
 	lookupTime := time.Now().UTC().Add(-1 * time.Minute)
@@ -137,6 +232,52 @@ func (s *fileSuite) Test_IndexTime_Error() {
 	s.Equal(int64(-1), offset)
 }
 
+func (s *fileSuite) Test_IndexTime_SkipMalformed() {
+	logs := `127.0.0.1 - frank [03/Mar/2022:02:41:40 +0000] "GET /api HTTP/1.0" 200 123
+127.0.0.1 - frank [03/Mar/2022:02:41:50 +0000] "GET /api HTTP/1.0" 200 123
+127.0.0.1 - frank [03/Mar/2022:02:4
+` + "\x00\x01\x02 binary junk\n" + `127.0.0.1 - frank [03/Mar/2022:02:42:10 +0000] "GET /api HTTP/1.0" 200 123
+127.0.0.1 - frank [03/Mar/2022:02:42:20 +0000] "GET /api HTTP/1.0" 200 123
+`
+	f := s.createLogs(logs)
+	defer func() { s.Require().NoError(f.Close()) }()
+	tests := []struct {
+		name        string
+		timeLookup  string
+		expectedLog string
+	}{
+		{
+			name:        "Before Malformed Lines",
+			timeLookup:  "03/Mar/2022:02:41:50 +0000",
+			expectedLog: `127.0.0.1 - frank [03/Mar/2022:02:41:50 +0000] "GET /api HTTP/1.0" 200 123`,
+		},
+		{
+			// the malformed lines are read, the reader applies its policy to them
+			name:        "Right After Malformed Lines",
+			timeLookup:  "03/Mar/2022:02:42:00 +0000",
+			expectedLog: "127.0.0.1 - frank [03/Mar/2022:02:4",
+		},
+		{
+			name:        "After Malformed Lines",
+			timeLookup:  "03/Mar/2022:02:42:20 +0000",
+			expectedLog: `127.0.0.1 - frank [03/Mar/2022:02:42:20 +0000] "GET /api HTTP/1.0" 200 123`,
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			file := NewFile(f, NewApacheParser())
+			file.skipMalformed = true
+			lookupTime, err := time.Parse(dateTimeFormat, test.timeLookup)
+			s.Require().NoError(err)
+
+			offset, err := file.IndexTime(lookupTime)
+
+			s.NoError(err)
+			s.Equal(test.expectedLog, s.readLogAt(f, offset))
+		})
+	}
+}
+
 func (s *fileSuite) Test_seekLine() {
 	data := "some\ntest\nstring\n"
 	f := s.createLogs(data)
@@ -144,7 +285,7 @@ func (s *fileSuite) Test_seekLine() {
 
 	_, err := f.Seek(8, io.SeekStart)
 	s.NoError(err)
-	file := NewFile(f)
+	file := NewFile(f, NewApacheParser())
 	s.NotNil(file)
 
 	tests := []struct {
@@ -200,49 +341,55 @@ func (s *fileSuite) Test_seekLine() {
 	}
 }
 
-func (s *fileSuite) Test_parseLogTime_Success() {
-	log := `127.0.0.1 user-identifier frank [04/Mar/2022:05:30:00 +0000] "GET /api/endpoint HTTP/1.0" 500 123`
-	expectedTime, err := time.Parse(dateTimeFormat, "04/Mar/2022:05:30:00 +0000")
-	s.Require().NoError(err)
-	file := NewFile(nil)
-	s.NotNil(file)
-
-	t, err := file.parseLogTime(log)
-
-	s.NoError(err)
-	s.True(t.Equal(expectedTime))
-}
-
-func (s *fileSuite) Test_parseLogTime_Error() {
-	file := NewFile(nil)
-	s.NotNil(file)
+func (s *fileSuite) Test_TimeRange() {
 	tests := []struct {
-		name        string
-		log         string
-		expectedErr string
+		name          string
+		logs          string
+		expectedFirst string
+		expectedLast  string
 	}{
 		{
-			name:        "Empty LogLine",
-			log:         "",
-			expectedErr: "line '': invalid log format",
+			name: "Multiple Logs",
+			logs: `
+127.0.0.1 user-identifier frank [04/Mar/2022:05:30:00 +0000] "GET /api/endpoint HTTP/1.0" 500 123
+127.0.0.1 user-identifier frank [04/Mar/2022:05:30:10 +0000] "GET /api/endpoint HTTP/1.0" 500 123
+127.0.0.1 user-identifier frank [04/Mar/2022:05:30:20 +0000] "GET /api/endpoint HTTP/1.0" 500 123
+`,
+			expectedFirst: "04/Mar/2022:05:30:00 +0000",
+			expectedLast:  "04/Mar/2022:05:30:20 +0000",
+		},
+		{
+			name:          "No Trailing New Line",
+			logs:          "127.0.0.1 user-identifier frank [04/Mar/2022:05:30:00 +0000] \"GET /api/endpoint HTTP/1.0\" 500 123\n127.0.0.1 user-identifier frank [04/Mar/2022:05:30:10 +0000] \"GET /api/endpoint HTTP/1.0\" 500 123",
+			expectedFirst: "04/Mar/2022:05:30:00 +0000",
+			expectedLast:  "04/Mar/2022:05:30:10 +0000",
 		},
 		{
-			name:        "Invalid LogLine",
-			log:         "this log line is not valid",
-			expectedErr: "line 'this log line is not valid': invalid log format",
+			name:          "Single Log",
+			logs:          "127.0.0.1 user-identifier frank [04/Mar/2022:05:30:00 +0000] \"GET /api/endpoint HTTP/1.0\" 500 123\n",
+			expectedFirst: "04/Mar/2022:05:30:00 +0000",
+			expectedLast:  "04/Mar/2022:05:30:00 +0000",
 		},
 		{
-			name:        "Invalid DateFormat",
-			log:         `127.0.0.1 user-identifier frank [36/Mar/2022:05:30:00 +0000] "GET /api/endpoint HTTP/1.0" 500 123`,
-			expectedErr: `parsing time "36/Mar/2022:05:30:00 +0000": day out of range`,
+			name: "No Logs",
+			logs: "\n\n",
 		},
 	}
 	for _, test := range tests {
 		s.Run(test.name, func() {
-			t, err := file.parseLogTime(test.log)
+			f := s.createLogs(test.logs)
+			defer func() { s.Require().NoError(f.Close()) }()
 
-			s.EqualError(err, test.expectedErr)
-			s.True(t.IsZero())
+			first, last, err := NewFile(f, NewApacheParser()).TimeRange()
+
+			s.NoError(err)
+			if test.expectedFirst == "" {
+				s.True(first.IsZero())
+				s.True(last.IsZero())
+				return
+			}
+			s.Equal(test.expectedFirst, first.Format(dateTimeFormat))
+			s.Equal(test.expectedLast, last.Format(dateTimeFormat))
 		})
 	}
 }
@@ -283,7 +430,7 @@ func BenchmarkSearch(b *testing.B) {
 	f, err := os.Open(path.Join(benchDataDir, "http-1.log"))
 	defer func() { require.NoError(b, f.Close()) }()
 	require.NoError(b, err)
-	file := NewFile(f)
+	file := NewFile(f, NewApacheParser())
 	require.NotNil(b, file)
 	b.ResetTimer()
 
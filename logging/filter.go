@@ -0,0 +1,431 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errInvalidFilter = errors.New("invalid filter")
+
+// ParseFilter compiles a filter expression evaluated on parsed log entries, i.e.
+// status >= 500 && method == "POST" && path =~ "^/api/"
+// ip in 10.0.0.0/8 || ua !~ "bot"
+// method in (GET, HEAD) && !(duration < 500ms)
+// Fields: ip (host), ident, user, method, path, protocol, status, bytes, referer, ua (agent), duration
+// and fields.<name> for the custom fields of the log format.
+// Operators: == != < <= > >= =~ (regexp match) !~ (regexp mismatch) in (list or CIDR), combined using && || ! and parentheses
+func ParseFilter(expr string) (*Filter, error) {
+	tokens, err := lexFilter(expr)
+	if err != nil {
+		return nil, fmt.Errorf("filter '%s': %v: %w", expr, err, errInvalidFilter)
+	}
+
+	p := &filterParser{tokens: tokens}
+	match, err := p.or()
+	if err == nil && p.peek().kind != tokenEOF {
+		err = p.unexpected(p.peek())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("filter '%s': %v: %w", expr, err, errInvalidFilter)
+	}
+
+	filter := &Filter{
+		expr:  expr,
+		match: match,
+	}
+	return filter, nil
+}
+
+// Filter represents a compiled filter expression
+type Filter struct {
+	expr  string
+	match predicate
+}
+
+// Match checks whether the log entry matches the filter
+func (f *Filter) Match(entry Entry) bool {
+	return f.match(&entry)
+}
+
+// String returns the filter expression
+func (f *Filter) String() string {
+	return f.expr
+}
+
+type predicate func(entry *Entry) bool
+
+type fieldKind int
+
+const (
+	stringField fieldKind = iota
+	numberField
+	durationField
+	ipField
+)
+
+// filterField represents an Entry field a filter can be applied on
+type filterField struct {
+	kind   fieldKind
+	text   func(entry *Entry) string
+	number func(entry *Entry) int64
+}
+
+var filterFields = map[string]filterField{
+	"ip":       {kind: ipField, text: func(e *Entry) string { return e.RemoteHost }},
+	"host":     {kind: ipField, text: func(e *Entry) string { return e.RemoteHost }},
+	"ident":    {kind: stringField, text: func(e *Entry) string { return e.Ident }},
+	"user":     {kind: stringField, text: func(e *Entry) string { return e.User }},
+	"method":   {kind: stringField, text: func(e *Entry) string { return e.Method }},
+	"path":     {kind: stringField, text: func(e *Entry) string { return e.Path }},
+	"protocol": {kind: stringField, text: func(e *Entry) string { return e.Protocol }},
+	"status":   {kind: numberField, number: func(e *Entry) int64 { return int64(e.Status) }},
+	"bytes":    {kind: numberField, number: func(e *Entry) int64 { return e.Bytes }},
+	"referer":  {kind: stringField, text: func(e *Entry) string { return e.Referer }},
+	"ua":       {kind: stringField, text: func(e *Entry) string { return e.UserAgent }},
+	"agent":    {kind: stringField, text: func(e *Entry) string { return e.UserAgent }},
+	"duration": {kind: durationField, number: func(e *Entry) int64 { return int64(e.Duration) }},
+}
+
+func lookupFilterField(name string) (filterField, error) {
+	if strings.HasPrefix(name, "fields.") {
+		key := strings.TrimPrefix(name, "fields.")
+		field := filterField{
+			kind: stringField,
+			text: func(e *Entry) string {
+				return e.Fields[key]
+			},
+		}
+		return field, nil
+	}
+
+	field, ok := filterFields[name]
+	if !ok {
+		return filterField{}, fmt.Errorf("unknown field '%s'", name)
+	}
+	return field, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenOperator
+	tokenLeftParen
+	tokenRightParen
+	tokenComma
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+// filterOperators are ordered so the longer operators are matched first
+var filterOperators = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "!~", "<", ">", "!"}
+
+// lexFilter splits a filter expression into tokens.
+// Words are anything but white spaces, quotes, parentheses, commas and operator characters, i.e. 10.0.0.0/8
+func lexFilter(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLeftParen, value: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRightParen, value: ")", pos: i})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{kind: tokenComma, value: ",", pos: i})
+			i++
+		case c == '"':
+			j := i + 1
+			for j < len(expr) && expr[j] != '"' {
+				if expr[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(expr) {
+				return nil, fmt.Errorf("position %d: unterminated string", i)
+			}
+			value, err := strconv.Unquote(expr[i : j+1])
+			if err != nil {
+				return nil, fmt.Errorf("position %d: %v", i, err)
+			}
+			tokens = append(tokens, token{kind: tokenString, value: value, pos: i})
+			i = j + 1
+		case strings.IndexByte("&|=!<>~", c) >= 0:
+			operator := ""
+			for _, op := range filterOperators {
+				if strings.HasPrefix(expr[i:], op) {
+					operator = op
+					break
+				}
+			}
+			if operator == "" {
+				return nil, fmt.Errorf("position %d: unknown operator '%c'", i, c)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, value: operator, pos: i})
+			i += len(operator)
+		default:
+			j := i
+			for j < len(expr) && strings.IndexByte(" \t\n\r(),\"&|=!<>~", expr[j]) < 0 {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenWord, value: expr[i:j], pos: i})
+			i = j
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(expr)}), nil
+}
+
+// filterParser is a recursive descent parser of the filter grammar:
+// or         = and { "||" and }
+// and        = unary { "&&" unary }
+// unary      = "!" unary | "(" or ")" | comparison
+// comparison = field operator value | field "in" ( "(" value { "," value } ")" | cidr )
+type filterParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *filterParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *filterParser) unexpected(t token) error {
+	if t.kind == tokenEOF {
+		return fmt.Errorf("position %d: unexpected end of filter", t.pos)
+	}
+	return fmt.Errorf("position %d: unexpected '%s'", t.pos, t.value)
+}
+
+func (p *filterParser) or() (predicate, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOperator && p.peek().value == "||" {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(e *Entry) bool { return l(e) || right(e) }
+	}
+	return left, nil
+}
+
+func (p *filterParser) and() (predicate, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOperator && p.peek().value == "&&" {
+		p.next()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(e *Entry) bool { return l(e) && right(e) }
+	}
+	return left, nil
+}
+
+func (p *filterParser) unary() (predicate, error) {
+	t := p.peek()
+	switch {
+	case t.kind == tokenOperator && t.value == "!":
+		p.next()
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(e *Entry) bool { return !operand(e) }, nil
+	case t.kind == tokenLeftParen:
+		p.next()
+		expr, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRightParen {
+			return nil, p.unexpected(closing)
+		}
+		return expr, nil
+	}
+	return p.comparison()
+}
+
+func (p *filterParser) comparison() (predicate, error) {
+	name := p.next()
+	if name.kind != tokenWord {
+		return nil, p.unexpected(name)
+	}
+	field, err := lookupFilterField(name.value)
+	if err != nil {
+		return nil, fmt.Errorf("position %d: %v", name.pos, err)
+	}
+
+	operator := p.next()
+	if operator.kind == tokenWord && operator.value == "in" {
+		return p.in(field)
+	}
+	if operator.kind != tokenOperator || operator.value == "&&" || operator.value == "||" || operator.value == "!" {
+		return nil, p.unexpected(operator)
+	}
+
+	value := p.next()
+	if value.kind != tokenWord && value.kind != tokenString {
+		return nil, p.unexpected(value)
+	}
+	match, err := compare(field, operator.value, value.value)
+	if err != nil {
+		return nil, fmt.Errorf("position %d: %v", value.pos, err)
+	}
+	return match, nil
+}
+
+// in parses either a list of values: method in (GET, HEAD) or a CIDR: ip in 10.0.0.0/8
+func (p *filterParser) in(field filterField) (predicate, error) {
+	if p.peek().kind != tokenLeftParen {
+		value := p.next()
+		if value.kind != tokenWord && value.kind != tokenString {
+			return nil, p.unexpected(value)
+		}
+		if field.kind != ipField {
+			return nil, fmt.Errorf("position %d: in requires a list of values", value.pos)
+		}
+		match, err := compare(field, "in", value.value)
+		if err != nil {
+			return nil, fmt.Errorf("position %d: %v", value.pos, err)
+		}
+		return match, nil
+	}
+
+	p.next()
+	var matches []predicate
+	for {
+		value := p.next()
+		if value.kind != tokenWord && value.kind != tokenString {
+			return nil, p.unexpected(value)
+		}
+		op := "=="
+		if field.kind == ipField && strings.Contains(value.value, "/") {
+			op = "in"
+		}
+		match, err := compare(field, op, value.value)
+		if err != nil {
+			return nil, fmt.Errorf("position %d: %v", value.pos, err)
+		}
+		matches = append(matches, match)
+
+		separator := p.next()
+		if separator.kind == tokenRightParen {
+			break
+		}
+		if separator.kind != tokenComma {
+			return nil, p.unexpected(separator)
+		}
+	}
+
+	return func(e *Entry) bool {
+		for _, match := range matches {
+			if match(e) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// compare creates the predicate comparing a field to a value using the given operator
+func compare(field filterField, op, value string) (predicate, error) {
+	switch op {
+	case "=~", "!~":
+		if field.text == nil {
+			return nil, fmt.Errorf("operator '%s' requires a text field", op)
+		}
+		regEx, err := regexp.Compile(value)
+		if err != nil {
+			return nil, err
+		}
+		negate := op == "!~"
+		return func(e *Entry) bool {
+			return regEx.MatchString(field.text(e)) != negate
+		}, nil
+	case "in":
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, err
+		}
+		return func(e *Entry) bool {
+			ip := net.ParseIP(field.text(e))
+			return ip != nil && network.Contains(ip)
+		}, nil
+	}
+
+	switch field.kind {
+	case numberField, durationField:
+		n, err := parseFilterNumber(field.kind, value)
+		if err != nil {
+			return nil, err
+		}
+		return compareNumber(field.number, op, n)
+	default:
+		if op != "==" && op != "!=" {
+			return nil, fmt.Errorf("operator '%s' requires a numeric field", op)
+		}
+		negate := op == "!="
+		return func(e *Entry) bool {
+			return (field.text(e) == value) != negate
+		}, nil
+	}
+}
+
+func parseFilterNumber(kind fieldKind, value string) (int64, error) {
+	if kind == durationField {
+		d, err := time.ParseDuration(value)
+		return int64(d), err
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+func compareNumber(number func(e *Entry) int64, op string, n int64) (predicate, error) {
+	switch op {
+	case "==":
+		return func(e *Entry) bool { return number(e) == n }, nil
+	case "!=":
+		return func(e *Entry) bool { return number(e) != n }, nil
+	case "<":
+		return func(e *Entry) bool { return number(e) < n }, nil
+	case "<=":
+		return func(e *Entry) bool { return number(e) <= n }, nil
+	case ">":
+		return func(e *Entry) bool { return number(e) > n }, nil
+	case ">=":
+		return func(e *Entry) bool { return number(e) >= n }, nil
+	}
+	return nil, fmt.Errorf("unknown operator '%s'", op)
+}
@@ -0,0 +1,137 @@
+package logging
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type filterSuite struct {
+	suite.Suite
+	entry Entry
+}
+
+func (s *filterSuite) SetupSuite() {
+	s.entry = Entry{
+		RemoteHost: "10.1.2.3",
+		User:       "frank",
+		Method:     "POST",
+		Path:       "/api/users",
+		Protocol:   "HTTP/1.1",
+		Status:     503,
+		Bytes:      2326,
+		UserAgent:  "Mozilla/5.0 (compatible; Googlebot/2.1)",
+		Duration:   750 * time.Millisecond,
+		Fields:     map[string]string{"X-Request-Id": "abc-123"},
+	}
+}
+
+func (s *filterSuite) Test_ParseFilter_Success() {
+	tests := []struct {
+		expr     string
+		expected bool
+	}{
+		{expr: `status >= 500`, expected: true},
+		{expr: `status < 500`, expected: false},
+		{expr: `status == 503 && method == "POST"`, expected: true},
+		{expr: `status >= 500 && method == "POST" && path =~ "^/api/"`, expected: true},
+		{expr: `path !~ "^/api/"`, expected: false},
+		{expr: `method == GET || bytes > 2000`, expected: true},
+		{expr: `method != POST`, expected: false},
+		{expr: `ip in 10.0.0.0/8`, expected: true},
+		{expr: `ip in 192.168.0.0/16`, expected: false},
+		{expr: `host == 10.1.2.3`, expected: true},
+		{expr: `ip in (127.0.0.1, 10.1.0.0/16)`, expected: true},
+		{expr: `method in (GET, HEAD)`, expected: false},
+		{expr: `method in ("GET", "POST")`, expected: true},
+		{expr: `ua =~ "(?i)bot"`, expected: true},
+		{expr: `duration >= 500ms && duration < 1s`, expected: true},
+		{expr: `!(duration < 500ms)`, expected: true},
+		{expr: `!(status >= 500 || user == frank)`, expected: false},
+		{expr: `status >= 500 && (method == GET || protocol == "HTTP/1.1")`, expected: true},
+		{expr: `fields.X-Request-Id == "abc-123"`, expected: true},
+		{expr: `fields.missing == ""`, expected: true},
+	}
+	for _, test := range tests {
+		s.Run(test.expr, func() {
+			filter, err := ParseFilter(test.expr)
+
+			s.Require().NoError(err)
+			s.Equal(test.expr, filter.String())
+			s.Equal(test.expected, filter.Match(s.entry))
+		})
+	}
+}
+
+func (s *filterSuite) Test_ParseFilter_Error() {
+	tests := []struct {
+		expr          string
+		expectedError string
+	}{
+		{
+			expr:          `status >=`,
+			expectedError: "filter 'status >=': position 9: unexpected end of filter: invalid filter",
+		},
+		{
+			expr:          `status >= 500 &&`,
+			expectedError: "filter 'status >= 500 &&': position 16: unexpected end of filter: invalid filter",
+		},
+		{
+			expr:          `latency > 5`,
+			expectedError: "filter 'latency > 5': position 0: unknown field 'latency': invalid filter",
+		},
+		{
+			expr:          `status > abc`,
+			expectedError: `filter 'status > abc': position 9: strconv.ParseInt: parsing "abc": invalid syntax: invalid filter`,
+		},
+		{
+			expr:          `method > GET`,
+			expectedError: "filter 'method > GET': position 9: operator '>' requires a numeric field: invalid filter",
+		},
+		{
+			expr:          `status =~ "5.."`,
+			expectedError: "filter 'status =~ \"5..\"': position 10: operator '=~' requires a text field: invalid filter",
+		},
+		{
+			expr:          `path =~ "["`,
+			expectedError: "filter 'path =~ \"[\"': position 8: error parsing regexp: missing closing ]: `[`: invalid filter",
+		},
+		{
+			expr:          `ip in 10.0.0.0`,
+			expectedError: "filter 'ip in 10.0.0.0': position 6: invalid CIDR address: 10.0.0.0: invalid filter",
+		},
+		{
+			expr:          `method in GET`,
+			expectedError: "filter 'method in GET': position 10: in requires a list of values: invalid filter",
+		},
+		{
+			expr:          `(status >= 500`,
+			expectedError: "filter '(status >= 500': position 14: unexpected end of filter: invalid filter",
+		},
+		{
+			expr:          `status >= 500 method == GET`,
+			expectedError: "filter 'status >= 500 method == GET': position 14: unexpected 'method': invalid filter",
+		},
+		{
+			expr:          `path == "/api`,
+			expectedError: "filter 'path == \"/api': position 8: unterminated string: invalid filter",
+		},
+		{
+			expr:          `status & 500`,
+			expectedError: "filter 'status & 500': position 7: unknown operator '&': invalid filter",
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.expr, func() {
+			filter, err := ParseFilter(test.expr)
+
+			s.EqualError(err, test.expectedError)
+			s.Nil(filter)
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	suite.Run(t, new(filterSuite))
+}
@@ -0,0 +1,246 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+const defaultFollowInterval = 250 * time.Millisecond
+
+// position represents the offset reached inside a log file
+type position struct {
+	name   string
+	offset int64
+}
+
+// follower keeps reading the logs appended to the newest log file, the same way tail -F does:
+// rename rotations are detected by the file changing its identity (inode),
+// copytruncate rotations are detected by the file shrinking,
+// and new log files appearing inside the directory are followed as soon as they show up
+type follower struct {
+	dir  string
+	name string
+	file *os.File
+	// lines keeps incomplete lines till the rest of the line is written
+	lines    *lineReader
+	newLines func(reader io.Reader, name string, offset int64) *lineReader
+	known    map[string]bool
+}
+
+// follow streams all the logs appended after the reading stopped, till the context is canceled
+func (r *Reader) follow(ctx context.Context, emit func(line Line) error) error {
+	interval := r.cfg.FollowInterval
+	if interval <= 0 {
+		interval = defaultFollowInterval
+	}
+
+	f := &follower{
+		dir: r.cfg.Directory,
+		newLines: func(reader io.Reader, name string, offset int64) *lineReader {
+			return r.lines(ctx, reader, name, offset)
+		},
+		known: map[string]bool{},
+	}
+	defer f.close()
+	for _, fi := range r.filesInfo {
+		f.known[fi.name] = true
+	}
+
+	tail := r.tail
+	if tail.name == "" && len(r.filesInfo) > 0 {
+		// nothing was read, follow the newest log file from its current end
+		newest := r.filesInfo[len(r.filesInfo)-1]
+		tail = position{name: newest.name, offset: -1}
+	}
+	if tail.name != "" {
+		err := f.open(tail.name, tail.offset)
+		if err != nil {
+			return err
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := f.poll(emit)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// open starts following the given file at the given offset, a negative offset means the end of the file
+func (f *follower) open(name string, offset int64) error {
+	file, err := os.Open(path.Join(f.dir, name))
+	if err != nil {
+		return err
+	}
+
+	whence := io.SeekStart
+	if offset < 0 {
+		offset, whence = 0, io.SeekEnd
+	}
+	offset, err = file.Seek(offset, whence)
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	f.close()
+	f.name = name
+	f.file = file
+	f.lines = f.newLines(file, name, offset)
+	f.lines.follow = true
+	f.known[name] = true
+	return nil
+}
+
+func (f *follower) close() {
+	if f.file != nil {
+		_ = f.file.Close()
+		f.file = nil
+	}
+}
+
+// poll emits the new logs of the followed file and handles rotations and new log files
+func (f *follower) poll(emit func(line Line) error) error {
+	if f.file != nil {
+		err := f.drain(emit)
+		if err != nil {
+			return err
+		}
+
+		rotated, err := f.rotated()
+		if err != nil {
+			return err
+		}
+		if rotated {
+			// the old file might have received some logs right before being rotated
+			err := f.drain(emit)
+			if err != nil {
+				return err
+			}
+			err = f.open(f.name, 0)
+			if err != nil {
+				return err
+			}
+			return f.drain(emit)
+		}
+	}
+
+	name, err := f.newFile()
+	if err != nil || name == "" {
+		return err
+	}
+	if f.file != nil {
+		err := f.drain(emit)
+		if err != nil {
+			return err
+		}
+	}
+	err = f.open(name, 0)
+	if err != nil {
+		return err
+	}
+	return f.drain(emit)
+}
+
+// drain emits all the complete lines appended to the followed file,
+// incomplete lines are kept till the rest of the line is written
+func (f *follower) drain(emit func(line Line) error) error {
+	for {
+		line, err := f.lines.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		err = emit(Line{Text: line, File: f.name, Offset: f.lines.start})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// rotated checks whether the followed file was rotated, the file name either points to a new file (rename rotation),
+// or the file was truncated (copytruncate rotation), in which case it's read again from the beginning.
+// A file that was renamed but not recreated yet is not considered rotated, it's still followed
+func (f *follower) rotated() (bool, error) {
+	current, err := os.Stat(path.Join(f.dir, f.name))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	followed, err := f.file.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	if !os.SameFile(current, followed) {
+		return true, nil
+	}
+	if followed.Size() < f.lines.offset {
+		_, err := f.file.Seek(0, io.SeekStart)
+		if err != nil {
+			return false, err
+		}
+		f.lines.reset(f.file, 0)
+	}
+	return false, nil
+}
+
+// newFile looks for a new log file inside the directory that should be followed instead of the current one.
+// Renamed (rotated) copies of the followed file, compressed files and files older than the followed one are ignored
+func (f *follower) newFile() (string, error) {
+	files, err := ioutil.ReadDir(f.dir)
+	if err != nil {
+		return "", err
+	}
+
+	var followed os.FileInfo
+	if f.file != nil {
+		followed, err = f.file.Stat()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	var newest os.FileInfo
+	for _, file := range files {
+		if f.known[file.Name()] || file.IsDir() || strings.HasSuffix(file.Name(), indexExtension) {
+			continue
+		}
+		f.known[file.Name()] = true
+
+		if followed != nil && (os.SameFile(file, followed) || file.ModTime().Before(followed.ModTime())) {
+			continue
+		}
+		compressed, err := isCompressed(path.Join(f.dir, file.Name()))
+		if err != nil || compressed {
+			continue
+		}
+		if newest == nil || file.ModTime().After(newest.ModTime()) {
+			newest = file
+		}
+	}
+
+	if newest == nil {
+		return "", nil
+	}
+	return newest.Name(), nil
+}
@@ -0,0 +1,146 @@
+package logging
+
+import (
+	"context"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type followSuite struct {
+	suite.Suite
+	dir   string
+	start time.Time
+}
+
+func (s *followSuite) SetupTest() {
+	start, err := time.Parse(dateTimeFormat, "03/Mar/2022:02:00:00 +0000")
+	s.Require().NoError(err)
+	s.start = start
+	s.dir = s.T().TempDir()
+}
+
+func (s *followSuite) Test_Follow_Success() {
+	s.writeLogs("http.log", os.O_CREATE|os.O_WRONLY, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	lines := make(chan string, 10)
+	reader, err := NewReader(ReaderConfig{
+		Directory:      s.dir,
+		From:           s.start,
+		Follow:         true,
+		FollowInterval: 5 * time.Millisecond,
+	})
+	s.Require().NoError(err)
+	done := make(chan error, 1)
+	go func() {
+		done <- reader.Read(ctx, lineWriter(lines))
+	}()
+	s.expectLogs(lines, 0)
+
+	// appended logs
+	s.writeLogs("http.log", os.O_APPEND|os.O_WRONLY, 1, 2)
+	s.expectLogs(lines, 1, 2)
+
+	// rename rotation: the rotated file still receives a log before the new file is created
+	s.Require().NoError(os.Rename(path.Join(s.dir, "http.log"), path.Join(s.dir, "http.log.1")))
+	s.writeLogs("http.log.1", os.O_APPEND|os.O_WRONLY, 3)
+	s.writeLogs("http.log", os.O_CREATE|os.O_WRONLY, 4, 5)
+	s.expectLogs(lines, 3, 4, 5)
+
+	// copytruncate rotation
+	s.writeLogs("http.log", os.O_TRUNC|os.O_WRONLY, 6)
+	s.expectLogs(lines, 6)
+
+	// new log file
+	s.writeLogs("http-2.log", os.O_CREATE|os.O_WRONLY, 7)
+	s.expectLogs(lines, 7)
+
+	cancel()
+	s.NoError(<-done)
+}
+
+func (s *followSuite) Test_Follow_PartialLine() {
+	s.writeLogs("http.log", os.O_CREATE|os.O_WRONLY, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lines := make(chan string, 10)
+	reader, err := NewReader(ReaderConfig{
+		Directory:      s.dir,
+		From:           s.start,
+		Follow:         true,
+		FollowInterval: 5 * time.Millisecond,
+	})
+	s.Require().NoError(err)
+	go func() {
+		_ = reader.Read(ctx, lineWriter(lines))
+	}()
+	s.expectLogs(lines, 0)
+
+	line := endpointLogs(s.start, 1, 2)
+	file, err := os.OpenFile(path.Join(s.dir, "http.log"), os.O_APPEND|os.O_WRONLY, 0644)
+	s.Require().NoError(err)
+	defer func() { _ = file.Close() }()
+	_, err = file.WriteString(line[:20])
+	s.Require().NoError(err)
+	select {
+	case l := <-lines:
+		s.Failf("incomplete log emitted", "log: %s", l)
+	case <-time.After(50 * time.Millisecond):
+	}
+	_, err = file.WriteString(line[20:])
+	s.Require().NoError(err)
+
+	s.expectLogs(lines, 1)
+}
+
+func (s *followSuite) Test_NewReader_FollowWithTo() {
+	reader, err := NewReader(ReaderConfig{
+		Directory: s.dir,
+		To:        s.start,
+		Follow:    true,
+	})
+
+	s.EqualError(err, "follow with to 2022-03-03 02:00:00 +0000 UTC: invalid time window")
+	s.Nil(reader)
+}
+
+func (s *followSuite) writeLogs(name string, flag int, logs ...int) {
+	file, err := os.OpenFile(path.Join(s.dir, name), flag, 0644)
+	s.Require().NoError(err)
+	defer func() { _ = file.Close() }()
+	for _, i := range logs {
+		_, err := file.WriteString(endpointLogs(s.start, i, i+1))
+		s.Require().NoError(err)
+	}
+}
+
+func (s *followSuite) expectLogs(lines chan string, logs ...int) {
+	for _, i := range logs {
+		select {
+		case line := <-lines:
+			s.Equal(endpointLogs(s.start, i, i+1), line)
+		case <-time.After(time.Second):
+			s.FailNow("timed out waiting for log", "log: %d", i)
+		}
+	}
+}
+
+// lineWriter sends every written log line over a channel
+type lineWriter chan string
+
+func (w lineWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.SplitAfter(string(p), "\n") {
+		if line != "" {
+			w <- line
+		}
+	}
+	return len(p), nil
+}
+
+func TestFollow(t *testing.T) {
+	suite.Run(t, new(followSuite))
+}
@@ -0,0 +1,417 @@
+package logging
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// clfTimePattern matches the apache/nginx default time representation without the surrounding brackets
+const clfTimePattern = `[\w:/]+\s[+\-]\d{4}`
+
+// CompileFormat compiles either an apache LogFormat or an nginx log_format into a line parser.
+// Formats containing nginx variables ($remote_addr) and no apache directives (%h) are considered nginx formats
+func CompileFormat(format string) (*RegexParser, error) {
+	trimmed := strings.TrimSpace(format)
+	if strings.HasPrefix(trimmed, "log_format") || (strings.Contains(trimmed, "$") && !strings.Contains(trimmed, "%")) {
+		return CompileNginxFormat(format)
+	}
+	return CompileApacheFormat(format)
+}
+
+// formatCompiler builds a regular expression out of a log format
+// keeping track of the setter for each regular expression group
+type formatCompiler struct {
+	pattern   strings.Builder
+	setters   []fieldSetter
+	timeIndex int
+	parseTime func(value string) (time.Time, error)
+	inQuotes  bool
+}
+
+// literal adds a literal piece of the log format, escaping it properly
+func (c *formatCompiler) literal(s string) {
+	for _, r := range s {
+		if r == '"' {
+			c.inQuotes = !c.inQuotes
+		}
+	}
+	c.pattern.WriteString(regexp.QuoteMeta(s))
+}
+
+// value returns the default pattern for a value depending on whether it's surrounded by quotes or not
+func (c *formatCompiler) value() string {
+	if c.inQuotes {
+		return `((?:[^"\\]|\\.)*)`
+	}
+	return `(\S*)`
+}
+
+// group adds a regular expression capturing group alongside the setter of its value
+func (c *formatCompiler) group(pattern string, setter fieldSetter) {
+	c.pattern.WriteString(pattern)
+	c.setters = append(c.setters, setter)
+}
+
+// timeGroup adds the capturing group of the log time, only the first time of the format is taken into account
+func (c *formatCompiler) timeGroup(pattern string, parseTime func(value string) (time.Time, error)) {
+	c.group(pattern, nil)
+	if c.parseTime != nil {
+		return
+	}
+
+	c.timeIndex = len(c.setters)
+	c.parseTime = parseTime
+}
+
+// request adds the capturing groups of a request line: "GET /api/endpoint HTTP/1.0"
+func (c *formatCompiler) request() {
+	c.group(`(\S+)`, entrySetters[methodGroupName])
+	c.group(`\s?(\S+)?`, entrySetters[pathGroupName])
+	c.group(`\s?(\S+)?`, entrySetters[protocolGroupName])
+}
+
+// compile compiles the regular expression and creates the line parser
+func (c *formatCompiler) compile(format string) (*RegexParser, error) {
+	if c.parseTime == nil {
+		return nil, fmt.Errorf("format '%s' has no time: %w", format, errInvalidLogFormat)
+	}
+
+	regEx, err := regexp.Compile("^" + c.pattern.String() + "$")
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &RegexParser{
+		regEx:     regEx,
+		timeIndex: c.timeIndex,
+		parseTime: c.parseTime,
+		// the first setter is for the whole match
+		setters: append([]fieldSetter{nil}, c.setters...),
+	}
+	return parser, nil
+}
+
+// CompileApacheFormat compiles an apache LogFormat into a line parser, i.e.
+// %h %l %u %t "%r" %>s %b "%{Referer}i" "%{User-agent}i"
+// The whole LogFormat directive is accepted as well: LogFormat "%h %l %u %t \"%r\" %>s %b" common
+func CompileApacheFormat(format string) (*RegexParser, error) {
+	f := unquoteApacheFormat(format)
+	c := &formatCompiler{}
+	for i := 0; i < len(f); i++ {
+		if f[i] != '%' {
+			j := strings.IndexByte(f[i:], '%')
+			if j < 0 {
+				j = len(f) - i
+			}
+			c.literal(f[i : i+j])
+			i += j - 1
+			continue
+		}
+
+		// skip modifiers like: %>s, %<s, %!200,304s, %400,501{User-agent}i
+		i++
+		for i < len(f) && strings.IndexByte("<>!,0123456789", f[i]) >= 0 {
+			i++
+		}
+		if i >= len(f) {
+			return nil, fmt.Errorf("format '%s' ends with an incomplete directive: %w", format, errInvalidLogFormat)
+		}
+
+		var arg string
+		if f[i] == '{' {
+			end := strings.IndexByte(f[i:], '}')
+			if end < 0 || i+end+1 >= len(f) {
+				return nil, fmt.Errorf("format '%s' has an unclosed directive argument: %w", format, errInvalidLogFormat)
+			}
+			arg = f[i+1 : i+end]
+			i += end + 1
+		}
+
+		err := c.apacheDirective(f[i], arg)
+		if err != nil {
+			return nil, fmt.Errorf("format '%s': %w", format, err)
+		}
+	}
+
+	return c.compile(format)
+}
+
+func (c *formatCompiler) apacheDirective(directive byte, arg string) error {
+	value := c.value()
+	switch directive {
+	case '%':
+		c.literal("%")
+	case 'a', 'h':
+		c.group(value, entrySetters[remoteHostGroupName])
+	case 'l':
+		c.group(value, entrySetters[identGroupName])
+	case 'u':
+		c.group(value, entrySetters[userGroupName])
+	case 't':
+		if arg == "" {
+			c.timeGroup(`\[(`+clfTimePattern+`)\]`, layoutTime(dateTimeFormat))
+			return nil
+		}
+		pattern, parseTime := strftime(strings.TrimPrefix(strings.TrimPrefix(arg, "begin:"), "end:"))
+		c.timeGroup("("+pattern+")", parseTime)
+	case 'r':
+		c.request()
+	case 'm':
+		c.group(value, entrySetters[methodGroupName])
+	case 'U':
+		c.group(value, entrySetters[pathGroupName])
+	case 'H':
+		c.group(value, entrySetters[protocolGroupName])
+	case 's':
+		c.group(`(\d{3}|-)`, entrySetters[statusGroupName])
+	case 'b', 'B':
+		c.group(`(\d+|-)`, entrySetters[bytesGroupName])
+	case 'D':
+		c.group(`(\d+|-)`, durationSetter(time.Microsecond))
+	case 'T':
+		unit := time.Second
+		switch arg {
+		case "ms":
+			unit = time.Millisecond
+		case "us":
+			unit = time.Microsecond
+		}
+		c.group(`(\d+|-)`, durationSetter(unit))
+	case 'i':
+		switch strings.ToLower(arg) {
+		case "referer":
+			c.group(value, entrySetters[refererGroupName])
+		case "user-agent":
+			c.group(value, entrySetters[userAgentGroupName])
+		default:
+			c.group(value, customFieldSetter(arg))
+		}
+	case 'C', 'e', 'n', 'o':
+		c.group(value, customFieldSetter(arg))
+	default:
+		name, ok := apacheFieldNames[directive]
+		if !ok {
+			return fmt.Errorf("unknown directive '%%%c': %w", directive, errInvalidLogFormat)
+		}
+		c.group(value, customFieldSetter(name))
+	}
+	return nil
+}
+
+// apacheFieldNames are the Entry.Fields names of the apache directives that have no dedicated Entry field
+var apacheFieldNames = map[byte]string{
+	'A': "local_ip",
+	'f': "filename",
+	'I': "bytes_received",
+	'k': "keepalive",
+	'L': "log_id",
+	'O': "bytes_sent",
+	'p': "port",
+	'P': "pid",
+	'q': "query",
+	'R': "handler",
+	'S': "bytes_transferred",
+	'v': "server_name",
+	'V': "server_name",
+	'X': "connection_status",
+}
+
+// unquoteApacheFormat extracts the format out of a whole LogFormat directive
+func unquoteApacheFormat(format string) string {
+	f := strings.TrimSpace(format)
+	if !strings.HasPrefix(f, "LogFormat") {
+		return f
+	}
+
+	f = strings.TrimSpace(strings.TrimPrefix(f, "LogFormat"))
+	if !strings.HasPrefix(f, `"`) {
+		return f
+	}
+	var b strings.Builder
+	for i := 1; i < len(f); i++ {
+		if f[i] == '\\' && i+1 < len(f) {
+			i++
+			b.WriteByte(f[i])
+			continue
+		}
+		if f[i] == '"' {
+			break
+		}
+		b.WriteByte(f[i])
+	}
+	return b.String()
+}
+
+// strftime converts a strftime time format into a regular expression and a time parser.
+// Besides strftime, apache supports: sec, msec, usec as the number of units since the unix epoch
+func strftime(format string) (string, func(value string) (time.Time, error)) {
+	switch format {
+	case "sec":
+		return `\d+`, unixTime(time.Second)
+	case "msec":
+		return `\d+`, unixTime(time.Millisecond)
+	case "usec":
+		return `\d+`, unixTime(time.Microsecond)
+	}
+
+	var pattern, layout strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 >= len(format) {
+			pattern.WriteString(regexp.QuoteMeta(format[i : i+1]))
+			layout.WriteByte(format[i])
+			continue
+		}
+
+		i++
+		directive, ok := strftimeDirectives[format[i]]
+		if !ok {
+			pattern.WriteString(regexp.QuoteMeta(format[i-1 : i+1]))
+			layout.WriteString(format[i-1 : i+1])
+			continue
+		}
+		pattern.WriteString(directive.pattern)
+		layout.WriteString(directive.layout)
+	}
+	return pattern.String(), layoutTime(layout.String())
+}
+
+var strftimeDirectives = map[byte]struct {
+	pattern string
+	layout  string
+}{
+	'a': {`[A-Za-z]{3}`, "Mon"},
+	'A': {`[A-Za-z]+`, "Monday"},
+	'b': {`[A-Za-z]{3}`, "Jan"},
+	'h': {`[A-Za-z]{3}`, "Jan"},
+	'B': {`[A-Za-z]+`, "January"},
+	'd': {`\d{2}`, "02"},
+	'e': {`[ \d]\d`, "_2"},
+	'm': {`\d{2}`, "01"},
+	'y': {`\d{2}`, "06"},
+	'Y': {`\d{4}`, "2006"},
+	'H': {`\d{2}`, "15"},
+	'I': {`\d{2}`, "03"},
+	'M': {`\d{2}`, "04"},
+	'S': {`\d{2}`, "05"},
+	'p': {`[AP]M`, "PM"},
+	'z': {`[+\-]\d{4}`, "-0700"},
+	'Z': {`[A-Z]+`, "MST"},
+	'T': {`\d{2}:\d{2}:\d{2}`, "15:04:05"},
+	'R': {`\d{2}:\d{2}`, "15:04"},
+	'D': {`\d{2}/\d{2}/\d{2}`, "01/02/06"},
+	'F': {`\d{4}-\d{2}-\d{2}`, "2006-01-02"},
+	'%': {`%`, "%"},
+}
+
+// CompileNginxFormat compiles an nginx log_format into a line parser, i.e.
+// $remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent "$http_referer" "$http_user_agent"
+// The whole log_format directive is accepted as well: log_format main '$remote_addr ...';
+func CompileNginxFormat(format string) (*RegexParser, error) {
+	f := unquoteNginxFormat(format)
+	c := &formatCompiler{}
+	for i := 0; i < len(f); i++ {
+		if f[i] != '$' {
+			j := strings.IndexByte(f[i:], '$')
+			if j < 0 {
+				j = len(f) - i
+			}
+			c.literal(f[i : i+j])
+			i += j - 1
+			continue
+		}
+
+		// variables are written either as $name or ${name}
+		i++
+		var name string
+		if i < len(f) && f[i] == '{' {
+			end := strings.IndexByte(f[i:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("format '%s' has an unclosed variable: %w", format, errInvalidLogFormat)
+			}
+			name = f[i+1 : i+end]
+			i += end
+		} else {
+			j := i
+			for j < len(f) && isNginxVariableChar(f[j]) {
+				j++
+			}
+			name = f[i:j]
+			i = j - 1
+		}
+		if name == "" {
+			return nil, fmt.Errorf("format '%s' has an empty variable: %w", format, errInvalidLogFormat)
+		}
+
+		c.nginxVariable(name)
+	}
+
+	return c.compile(format)
+}
+
+func (c *formatCompiler) nginxVariable(name string) {
+	value := c.value()
+	switch name {
+	case "remote_addr", "binary_remote_addr", "realip_remote_addr":
+		c.group(value, entrySetters[remoteHostGroupName])
+	case "remote_user":
+		c.group(value, entrySetters[userGroupName])
+	case "time_local":
+		c.timeGroup("("+clfTimePattern+")", layoutTime(dateTimeFormat))
+	case "time_iso8601":
+		c.timeGroup(`(\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:Z|[+\-]\d{2}:\d{2}))`, layoutTime(time.RFC3339))
+	case "msec":
+		c.timeGroup(`(\d+\.\d+)`, unixTime(time.Second))
+	case "request":
+		c.request()
+	case "request_method":
+		c.group(value, entrySetters[methodGroupName])
+	case "request_uri", "uri", "document_uri":
+		c.group(value, entrySetters[pathGroupName])
+	case "server_protocol":
+		c.group(value, entrySetters[protocolGroupName])
+	case "status":
+		c.group(`(\d{3}|-)`, entrySetters[statusGroupName])
+	case "body_bytes_sent":
+		c.group(`(\d+|-)`, entrySetters[bytesGroupName])
+	case "http_referer":
+		c.group(value, entrySetters[refererGroupName])
+	case "http_user_agent":
+		c.group(value, entrySetters[userAgentGroupName])
+	case "request_time":
+		c.group(`(\d+(?:\.\d+)?|-)`, durationSetter(time.Second))
+	default:
+		c.group(value, customFieldSetter(name))
+	}
+}
+
+func isNginxVariableChar(b byte) bool {
+	return b == '_' || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+}
+
+// unquoteNginxFormat extracts the format out of a whole log_format directive
+// concatenating all its quoted strings, i.e. log_format main '$remote_addr - ' '"$request" $status';
+func unquoteNginxFormat(format string) string {
+	f := strings.TrimSpace(format)
+	if !strings.HasPrefix(f, "log_format") {
+		return f
+	}
+
+	var b strings.Builder
+	var quote byte
+	for i := 0; i < len(f); i++ {
+		switch {
+		case quote == 0 && (f[i] == '\'' || f[i] == '"'):
+			quote = f[i]
+		case quote != 0 && f[i] == '\\' && i+1 < len(f):
+			i++
+			b.WriteByte(f[i])
+		case quote != 0 && f[i] == quote:
+			quote = 0
+		case quote != 0:
+			b.WriteByte(f[i])
+		}
+	}
+	return b.String()
+}
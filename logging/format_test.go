@@ -0,0 +1,195 @@
+package logging
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type formatSuite struct {
+	suite.Suite
+}
+
+func (s *formatSuite) Test_CompileFormat_Success() {
+	logTime, err := time.Parse(dateTimeFormat, "04/Mar/2022:05:30:00 +0000")
+	s.Require().NoError(err)
+	tests := []struct {
+		name          string
+		format        string
+		log           string
+		expectedEntry Entry
+	}{
+		{
+			name:   "Apache Common",
+			format: `%h %l %u %t "%r" %>s %b`,
+			log:    `127.0.0.1 - frank [04/Mar/2022:05:30:00 +0000] "GET /api/endpoint HTTP/1.0" 500 123`,
+			expectedEntry: Entry{
+				RemoteHost: "127.0.0.1",
+				Ident:      "-",
+				User:       "frank",
+				Time:       logTime,
+				Method:     "GET",
+				Path:       "/api/endpoint",
+				Protocol:   "HTTP/1.0",
+				Status:     500,
+				Bytes:      123,
+			},
+		},
+		{
+			name:   "Apache Custom Directive",
+			format: `LogFormat "%h %l %u %t \"%r\" %>s %b %D \"%{X-Request-Id}i\" \"%{User-agent}i\"" custom`,
+			log:    `10.0.0.1 - - [04/Mar/2022:05:30:00 +0000] "POST /api/users HTTP/1.1" 201 - 1500 "abc-123" "curl/7.79.1"`,
+			expectedEntry: Entry{
+				RemoteHost: "10.0.0.1",
+				Ident:      "-",
+				User:       "-",
+				Time:       logTime,
+				Method:     "POST",
+				Path:       "/api/users",
+				Protocol:   "HTTP/1.1",
+				Status:     201,
+				UserAgent:  "curl/7.79.1",
+				Duration:   1500 * time.Microsecond,
+				Fields:     map[string]string{"X-Request-Id": "abc-123"},
+			},
+		},
+		{
+			name:   "Apache Strftime Time",
+			format: `%a [%{%Y-%m-%d %H:%M:%S %z}t] "%m %U" %s %{ms}T`,
+			log:    `10.0.0.1 [2022-03-04 05:30:00 +0000] "GET /health" 200 12`,
+			expectedEntry: Entry{
+				RemoteHost: "10.0.0.1",
+				Time:       logTime,
+				Method:     "GET",
+				Path:       "/health",
+				Status:     200,
+				Duration:   12 * time.Millisecond,
+			},
+		},
+		{
+			name:   "Nginx Combined",
+			format: `$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent "$http_referer" "$http_user_agent"`,
+			log:    `10.0.0.2 - - [04/Mar/2022:05:30:00 +0000] "GET /index.html HTTP/2.0" 200 612 "-" "Mozilla/5.0 (X11; Linux x86_64)"`,
+			expectedEntry: Entry{
+				RemoteHost: "10.0.0.2",
+				User:       "-",
+				Time:       logTime,
+				Method:     "GET",
+				Path:       "/index.html",
+				Protocol:   "HTTP/2.0",
+				Status:     200,
+				Bytes:      612,
+				Referer:    "-",
+				UserAgent:  "Mozilla/5.0 (X11; Linux x86_64)",
+			},
+		},
+		{
+			name: "Nginx Directive",
+			format: `log_format timed '$remote_addr [$time_iso8601] "$request" '
+                  '$status $request_time ${http_x_request_id}';`,
+			log: `10.0.0.3 [2022-03-04T05:30:00+00:00] "DELETE /api/users/1 HTTP/1.1" 204 0.250 abc-123`,
+			expectedEntry: Entry{
+				RemoteHost: "10.0.0.3",
+				Time:       logTime,
+				Method:     "DELETE",
+				Path:       "/api/users/1",
+				Protocol:   "HTTP/1.1",
+				Status:     204,
+				Duration:   250 * time.Millisecond,
+				Fields:     map[string]string{"http_x_request_id": "abc-123"},
+			},
+		},
+		{
+			name:   "Nginx Msec",
+			format: `$msec $remote_addr $status`,
+			log:    `1646371800.000 10.0.0.4 200`,
+			expectedEntry: Entry{
+				RemoteHost: "10.0.0.4",
+				Time:       logTime,
+				Status:     200,
+			},
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			parser, err := CompileFormat(test.format)
+			s.Require().NoError(err)
+
+			entry, err := parser.ParseEntry(test.log)
+			s.NoError(err)
+			s.True(test.expectedEntry.Time.Equal(entry.Time))
+			entry.Time = test.expectedEntry.Time
+			s.Equal(test.expectedEntry, entry)
+
+			t, err := parser.ParseTime(test.log)
+			s.NoError(err)
+			s.True(test.expectedEntry.Time.Equal(t))
+		})
+	}
+}
+
+func (s *formatSuite) Test_CompileFormat_Error() {
+	tests := []struct {
+		name        string
+		format      string
+		expectedErr string
+	}{
+		{
+			name:        "Apache No Time",
+			format:      `%h %l %u "%r" %>s %b`,
+			expectedErr: `format '%h %l %u "%r" %>s %b' has no time: invalid log format`,
+		},
+		{
+			name:        "Apache Unknown Directive",
+			format:      `%h %t %J`,
+			expectedErr: `format '%h %t %J': unknown directive '%J': invalid log format`,
+		},
+		{
+			name:        "Apache Unclosed Argument",
+			format:      `%h %t %{Referer`,
+			expectedErr: `format '%h %t %{Referer' has an unclosed directive argument: invalid log format`,
+		},
+		{
+			name:        "Nginx No Time",
+			format:      `$remote_addr $status`,
+			expectedErr: `format '$remote_addr $status' has no time: invalid log format`,
+		},
+		{
+			name:        "Nginx Unclosed Variable",
+			format:      `$time_local ${status`,
+			expectedErr: `format '$time_local ${status' has an unclosed variable: invalid log format`,
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			parser, err := CompileFormat(test.format)
+
+			s.EqualError(err, test.expectedErr)
+			s.Nil(parser)
+		})
+	}
+}
+
+func (s *formatSuite) Test_IndexTime_CompiledFormat() {
+	parser, err := CompileFormat(`%h [%{%Y-%m-%d %H:%M:%S}t] %>s`)
+	s.Require().NoError(err)
+	logs := "10.0.0.1 [2022-03-04 05:30:00] 200\n10.0.0.1 [2022-03-04 05:31:00] 200\n10.0.0.1 [2022-03-04 05:32:00] 200\n"
+	f, err := os.CreateTemp(s.T().TempDir(), "*-http.log")
+	s.Require().NoError(err)
+	defer func() { s.Require().NoError(f.Close()) }()
+	_, err = f.WriteString(logs)
+	s.Require().NoError(err)
+	lookupTime, err := time.Parse("2006-01-02 15:04:05", "2022-03-04 05:30:30")
+	s.Require().NoError(err)
+
+	offset, err := NewFile(f, parser).IndexTime(lookupTime)
+
+	s.NoError(err)
+	s.Equal(int64(35), offset)
+}
+
+func TestFormat(t *testing.T) {
+	suite.Run(t, new(formatSuite))
+}
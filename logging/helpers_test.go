@@ -0,0 +1,61 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testStart is the time the test logs start at
+var testStart = time.Date(2022, time.March, 3, 2, 0, 0, 0, time.UTC)
+
+// testLog formats the apache log line of a GET request to the given path answered with the given status at the given time
+func testLog(t time.Time, path string, status int) string {
+	return fmt.Sprintf(
+		"127.0.0.1 user-identifier frank [%s] \"GET %s HTTP/1.0\" %d 123\n",
+		t.Format(dateTimeFormat), path, status,
+	)
+}
+
+// testLogs generates the logs of the given range, each log happens a second after the previous one
+// and has its index as path, every fifth log failed
+func testLogs(start time.Time, from, to int) string {
+	var logs strings.Builder
+	for i := from; i < to; i++ {
+		status := 200
+		if i%5 == 0 {
+			status = 500
+		}
+		logs.WriteString(testLog(start.Add(time.Duration(i)*time.Second), fmt.Sprintf("/%d", i), status))
+	}
+	return logs.String()
+}
+
+// endpointLogs generates the logs of the given range, each log happens a second after the previous one,
+// they are all failed requests to the same endpoint, so every log line is 98 bytes long
+func endpointLogs(start time.Time, from, to int) string {
+	var logs strings.Builder
+	for i := from; i < to; i++ {
+		logs.WriteString(testLog(start.Add(time.Duration(i)*time.Second), "/api/endpoint", 500))
+	}
+	return logs.String()
+}
+
+// writeTestLogs writes the logs to the named log file, setting its modification time
+func writeTestLogs(t *testing.T, name, logs string, modified time.Time) {
+	require.NoError(t, os.WriteFile(name, []byte(logs), 0644))
+	require.NoError(t, os.Chtimes(name, modified, modified))
+}
+
+// appendTestLogs appends the logs to the named log file
+func appendTestLogs(t *testing.T, name, logs string) {
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+	_, err = f.WriteString(logs)
+	require.NoError(t, err)
+}
@@ -0,0 +1,193 @@
+package logging
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// indexExtension is the extension of the sidecar index stored next to the indexed file
+const indexExtension = ".idx"
+
+// DefaultIndexInterval is the default amount of logs between two entries of the sidecar index of a plain log file
+const DefaultIndexInterval = 256 * 1024 // 256KB
+
+var errCompressedFile = errors.New("compressed file")
+
+// TimeIndex represents a sparse index mapping log times to offsets inside a log file.
+// It's stored as a sidecar file next to the indexed file: access.log.gz -> access.log.gz.idx
+type TimeIndex struct {
+	// Size is the size of the indexed file, used to detect whether the index is still valid.
+	// Plain log files keep growing, so their Size is the size of the indexed part, up to the last complete line
+	Size    int64        `json:"size"`
+	Entries []IndexEntry `json:"entries"`
+}
+
+// IndexEntry represents the time of the first log found at a given offset
+type IndexEntry struct {
+	Time   time.Time `json:"time"`
+	Offset int64     `json:"offset"`
+}
+
+// ReadIndex reads the sidecar index of the given log file
+func ReadIndex(name string) (*TimeIndex, error) {
+	data, err := os.ReadFile(name + indexExtension)
+	if err != nil {
+		return nil, err
+	}
+
+	var index TimeIndex
+	err = json.Unmarshal(data, &index)
+	if err != nil {
+		return nil, err
+	}
+	return &index, nil
+}
+
+// WriteFile stores the index as the sidecar index of the given log file
+func (index *TimeIndex) WriteFile(name string) error {
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name+indexExtension, data, 0644)
+}
+
+// lookup returns the offset of the last index entry older than the lookup time,
+// the log at or after the lookup time can't be found before that offset
+func (index *TimeIndex) lookup(lookupTime time.Time) int64 {
+	i := sort.Search(len(index.Entries), func(i int) bool {
+		return !index.Entries[i].Time.Before(lookupTime)
+	})
+	if i == 0 {
+		return 0
+	}
+	return index.Entries[i-1].Offset
+}
+
+// bounds narrows down the binary search of the first log for which the after function returns true
+// to the logs between two index entries
+func (index *TimeIndex) bounds(after func(logTime time.Time) bool, size int64) (top, bottom int64) {
+	i := sort.Search(len(index.Entries), func(i int) bool {
+		return after(index.Entries[i].Time)
+	})
+	top, bottom = 0, size
+	if i > 0 {
+		top = index.Entries[i-1].Offset
+	}
+	if i < len(index.Entries) {
+		bottom = index.Entries[i].Offset
+	}
+	return top, bottom
+}
+
+// IndexFile builds the sidecar index of a plain log file, recording the time and the offset
+// of the first log found every interval bytes, so File.IndexTime only has to search between two index entries.
+// An existing index is extended with the logs appended since it was built, only the new logs are scanned,
+// while an index that is no longer valid (i.e. the file was truncated or rotated) is built all over again
+func IndexFile(name string, parser LineParser, interval int64) (*TimeIndex, error) {
+	if interval <= 0 {
+		interval = DefaultIndexInterval
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	compressed, err := isCompressed(name)
+	if err != nil {
+		return nil, err
+	}
+	if compressed {
+		return nil, fmt.Errorf("index '%s': %w", name, errCompressedFile)
+	}
+
+	file := NewFile(f, parser)
+	index := file.index()
+	if index == nil {
+		index = &TimeIndex{}
+	}
+
+	_, err = f.Seek(index.Size, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+	offset, next := index.Size, int64(0)
+	if len(index.Entries) > 0 {
+		next = index.Entries[len(index.Entries)-1].Offset + interval
+	}
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadString('\n')
+		// the last line may still be being written, it's indexed once it's complete
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if offset >= next && strings.TrimSpace(line) != "" {
+			logTime, parseErr := parser.ParseTime(strings.TrimRight(line, "\r\n"))
+			if parseErr == nil {
+				index.Entries = append(index.Entries, IndexEntry{Time: logTime, Offset: offset})
+				next = offset + interval
+			}
+		}
+		offset += int64(len(line))
+	}
+	index.Size = offset
+
+	err = index.WriteFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// index returns the sidecar index of the log file, nil is returned when there is no valid index.
+// The index is read and validated once, then kept for the following searches
+func (file *File) index() *TimeIndex {
+	if !file.timeIndexLoaded {
+		file.timeIndex = file.loadIndex()
+		file.timeIndexLoaded = true
+	}
+	return file.timeIndex
+}
+
+// loadIndex reads the sidecar index of the log file and validates it.
+// An index is valid as long as the file did not shrink and its first and last entries still point to logs of the same time,
+// otherwise the file was truncated or replaced (rotated) since it was indexed
+func (file *File) loadIndex() *TimeIndex {
+	index, err := ReadIndex(file.Name())
+	if err != nil || len(index.Entries) == 0 {
+		return nil
+	}
+	stat, err := file.Stat()
+	if err != nil || stat.Size() < index.Size {
+		return nil
+	}
+
+	for _, entry := range []IndexEntry{index.Entries[0], index.Entries[len(index.Entries)-1]} {
+		_, err := file.Seek(entry.Offset, io.SeekStart)
+		if err != nil {
+			return nil
+		}
+		line, err := bufio.NewReader(file).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil
+		}
+		logTime, err := file.parser.ParseTime(strings.TrimRight(line, "\r\n"))
+		if err != nil || !logTime.Equal(entry.Time) {
+			return nil
+		}
+	}
+	return index
+}
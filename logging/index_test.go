@@ -0,0 +1,182 @@
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type indexSuite struct {
+	suite.Suite
+	name  string
+	start time.Time
+}
+
+func (s *indexSuite) SetupTest() {
+	start, err := time.Parse(dateTimeFormat, "03/Mar/2022:02:00:00 +0000")
+	s.Require().NoError(err)
+	s.start = start
+	s.name = path.Join(s.T().TempDir(), "http.log")
+	s.Require().NoError(os.WriteFile(s.name, []byte(endpointLogs(s.start, 0, 100)), 0644))
+}
+
+func (s *indexSuite) Test_IndexFile_Success() {
+	// each log line is 98 bytes long, so there is an index entry every 10 logs
+	index, err := IndexFile(s.name, NewApacheParser(), 980)
+	s.Require().NoError(err)
+
+	s.Equal(int64(9800), index.Size)
+	s.Len(index.Entries, 10)
+	for i, entry := range index.Entries {
+		s.Equal(int64(i*980), entry.Offset)
+		s.True(entry.Time.Equal(s.start.Add(time.Duration(i*10)*time.Second)), entry.Time.String())
+	}
+	stored, err := ReadIndex(s.name)
+	s.Require().NoError(err)
+	s.Equal(index.Size, stored.Size)
+	s.Len(stored.Entries, 10)
+}
+
+func (s *indexSuite) Test_IndexFile_Extend() {
+	s.Require().NoError(os.WriteFile(s.name, []byte(endpointLogs(s.start, 0, 50)), 0644))
+	_, err := IndexFile(s.name, NewApacheParser(), 980)
+	s.Require().NoError(err)
+	f, err := os.OpenFile(s.name, os.O_APPEND|os.O_WRONLY, 0644)
+	s.Require().NoError(err)
+	// the last log is still being written
+	_, err = f.WriteString(endpointLogs(s.start, 50, 100) + "127.0.0.1 user-identifier")
+	s.Require().NoError(err)
+	s.Require().NoError(f.Close())
+
+	index, err := IndexFile(s.name, NewApacheParser(), 980)
+	s.Require().NoError(err)
+
+	s.Equal(int64(9800), index.Size)
+	s.Len(index.Entries, 10)
+	s.Equal(int64(5*980), index.Entries[5].Offset)
+}
+
+func (s *indexSuite) Test_IndexFile_Rotated() {
+	_, err := IndexFile(s.name, NewApacheParser(), 980)
+	s.Require().NoError(err)
+	later := s.start.Add(time.Hour)
+	s.Require().NoError(os.WriteFile(s.name, []byte(endpointLogs(later, 0, 200)), 0644))
+
+	index, err := IndexFile(s.name, NewApacheParser(), 980)
+	s.Require().NoError(err)
+
+	s.Equal(int64(19600), index.Size)
+	s.Len(index.Entries, 20)
+	s.True(index.Entries[0].Time.Equal(later))
+}
+
+func (s *indexSuite) Test_IndexFile_Compressed() {
+	archive := &bytes.Buffer{}
+	_, err := Compress(archive, strings.NewReader(endpointLogs(s.start, 0, 10)), NewApacheParser(), 0)
+	s.Require().NoError(err)
+	name := s.name + ".gz"
+	s.Require().NoError(os.WriteFile(name, archive.Bytes(), 0644))
+
+	index, err := IndexFile(name, NewApacheParser(), 980)
+
+	s.Nil(index)
+	s.EqualError(err, fmt.Sprintf("index '%s': compressed file", name))
+}
+
+func (s *indexSuite) Test_IndexTime_Index() {
+	_, err := IndexFile(s.name, NewApacheParser(), 980)
+	s.Require().NoError(err)
+	// the index is stale once the file is replaced, the logs are then searched without it
+	rotated := path.Join(path.Dir(s.name), "rotated.log")
+	s.Require().NoError(os.WriteFile(rotated, []byte(endpointLogs(s.start.Add(-time.Minute), 0, 200)), 0644))
+	index, err := ReadIndex(s.name)
+	s.Require().NoError(err)
+	s.Require().NoError(index.WriteFile(rotated))
+
+	tests := []struct {
+		name           string
+		file           string
+		lookup         time.Duration
+		expectedOffset int64
+	}{
+		{
+			name:           "First Log",
+			file:           s.name,
+			lookup:         -time.Hour,
+			expectedOffset: 0,
+		},
+		{
+			name:           "Indexed Log",
+			file:           s.name,
+			lookup:         30 * time.Second,
+			expectedOffset: 30 * 98,
+		},
+		{
+			name:           "Between Index Entries",
+			file:           s.name,
+			lookup:         57 * time.Second,
+			expectedOffset: 57 * 98,
+		},
+		{
+			name:           "After Last Index Entry",
+			file:           s.name,
+			lookup:         95 * time.Second,
+			expectedOffset: 95 * 98,
+		},
+		{
+			name:           "Newer Than All Logs",
+			file:           s.name,
+			lookup:         time.Hour,
+			expectedOffset: -1,
+		},
+		{
+			name:           "Stale Index",
+			file:           rotated,
+			lookup:         30 * time.Second,
+			expectedOffset: 90 * 98,
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			f, err := os.Open(test.file)
+			s.Require().NoError(err)
+			defer func() { _ = f.Close() }()
+
+			offset, err := NewFile(f, NewApacheParser()).IndexTime(s.start.Add(test.lookup))
+
+			s.NoError(err)
+			s.Equal(test.expectedOffset, offset)
+		})
+	}
+}
+
+func (s *indexSuite) Test_IndexTime_IndexLoadedOnce() {
+	_, err := IndexFile(s.name, NewApacheParser(), 980)
+	s.Require().NoError(err)
+	f, err := os.Open(s.name)
+	s.Require().NoError(err)
+	defer func() { _ = f.Close() }()
+	file := NewFile(f, NewApacheParser())
+
+	offset, err := file.IndexTime(s.start.Add(30 * time.Second))
+	s.Require().NoError(err)
+	s.Equal(int64(30*98), offset)
+	index := file.index()
+	s.Require().NotNil(index)
+	s.Require().NoError(os.Remove(s.name + indexExtension))
+
+	s.Same(index, file.index())
+	offset, err = file.IndexTime(s.start.Add(57 * time.Second))
+	s.NoError(err)
+	s.Equal(int64(57*98), offset)
+}
+
+func TestIndex(t *testing.T) {
+	suite.Run(t, new(indexSuite))
+}
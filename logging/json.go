@@ -0,0 +1,278 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JSON time formats besides any custom time layout
+const (
+	JSONTimeAuto      = "auto"
+	JSONTimeRFC3339   = "rfc3339"
+	JSONTimeUnix      = "unix"
+	JSONTimeUnixMilli = "unixmilli"
+	JSONTimeUnixMicro = "unixmicro"
+	JSONTimeUnixNano  = "unixnano"
+)
+
+var (
+	// jsonTimeFields are the fields looked up for the time when no time field is configured
+	jsonTimeFields = []string{"time", "ts", "timestamp", "@timestamp", "StartUTC"}
+	// jsonEntryFields are the paths looked up for each Entry field, in order,
+	// covering the most common access logs: caddy, traefik and generic application logs
+	jsonEntryFields = map[string][]string{
+		remoteHostGroupName: {"remote_ip", "remote_addr", "client_ip", "ip", "request.remote_ip", "request.client_ip", "ClientHost"},
+		userGroupName:       {"user", "user_id", "request.user_id"},
+		methodGroupName:     {"method", "request.method", "RequestMethod"},
+		pathGroupName:       {"path", "uri", "url", "request.uri", "RequestPath"},
+		protocolGroupName:   {"protocol", "proto", "request.proto", "RequestProtocol"},
+		statusGroupName:     {"status", "status_code", "DownstreamStatus"},
+		bytesGroupName:      {"bytes", "size", "bytes_sent", "DownstreamContentSize"},
+		refererGroupName:    {"referer", "request.headers.Referer", "request_Referer"},
+		userAgentGroupName:  {"user_agent", "request.headers.User-Agent", "request_User-Agent"},
+	}
+)
+
+// NewJSONParser creates a line parser for JSON lines logs (one JSON object per line).
+// timeField is a dot separated path to the time of the log, i.e. "ts" or "request.time",
+// when empty the most common time fields are looked up: time, ts, timestamp, @timestamp.
+// timeFormat is one of: auto, rfc3339, unix, unixmilli, unixmicro, unixnano or a custom time layout,
+// auto detects RFC3339 strings and unix timestamps by their magnitude
+func NewJSONParser(timeField, timeFormat string) *JSONParser {
+	if timeFormat == "" {
+		timeFormat = JSONTimeAuto
+	}
+	return &JSONParser{
+		timeField:  timeField,
+		timeFormat: timeFormat,
+	}
+}
+
+func newDefaultJSONParser() LineParser {
+	return NewJSONParser("", JSONTimeAuto)
+}
+
+// JSONParser represents a line parser for JSON lines logs
+type JSONParser struct {
+	timeField  string
+	timeFormat string
+}
+
+// ParseTime parses a given JSON log line and attempts to convert its time field into time.Time
+func (p *JSONParser) ParseTime(l string) (time.Time, error) {
+	object, err := p.decode(l)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return p.parseTime(l, object)
+}
+
+// ParseEntry parses a given JSON log line into an Entry.
+// Top level fields that don't map to any Entry field are stored inside Entry.Fields
+func (p *JSONParser) ParseEntry(l string) (Entry, error) {
+	object, err := p.decode(l)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	t, err := p.parseTime(l, object)
+	if err != nil {
+		return Entry{}, err
+	}
+	entry := Entry{Time: t}
+
+	used := map[string]bool{}
+	for name, paths := range jsonEntryFields {
+		for _, path := range paths {
+			value, ok := lookupJSON(object, path)
+			if !ok {
+				continue
+			}
+
+			used[strings.SplitN(path, ".", 2)[0]] = true
+			err := entrySetters[name](&entry, jsonString(value))
+			if err != nil {
+				return Entry{}, fmt.Errorf("line '%s': %s: %w", l, path, err)
+			}
+			break
+		}
+	}
+	err = p.setDuration(&entry, object, used)
+	if err != nil {
+		return Entry{}, fmt.Errorf("line '%s': %w", l, err)
+	}
+
+	for key, value := range object {
+		if used[key] || key == p.timeField || isJSONTimeField(key) {
+			continue
+		}
+		if _, ok := value.(map[string]interface{}); ok {
+			continue
+		}
+		if entry.Fields == nil {
+			entry.Fields = make(map[string]string)
+		}
+		entry.Fields[key] = jsonString(value)
+	}
+
+	return entry, nil
+}
+
+// setDuration sets the request duration using caddy's (seconds) or traefik's (nanoseconds) duration fields,
+// Go duration strings like "1.5ms" are accepted as well
+func (p *JSONParser) setDuration(entry *Entry, object map[string]interface{}, used map[string]bool) error {
+	if value, ok := object["duration"]; ok {
+		used["duration"] = true
+		if s, ok := value.(string); ok {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return err
+			}
+			entry.Duration = d
+			return nil
+		}
+		return durationSetter(time.Second)(entry, jsonString(value))
+	}
+	if value, ok := object["Duration"]; ok {
+		used["Duration"] = true
+		return durationSetter(time.Nanosecond)(entry, jsonString(value))
+	}
+	return nil
+}
+
+func (p *JSONParser) decode(l string) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(strings.NewReader(l))
+	decoder.UseNumber()
+	var object map[string]interface{}
+	err := decoder.Decode(&object)
+	if err != nil || object == nil {
+		return nil, fmt.Errorf("line '%s': %w", l, errInvalidLogFormat)
+	}
+	return object, nil
+}
+
+func (p *JSONParser) parseTime(l string, object map[string]interface{}) (time.Time, error) {
+	var value interface{}
+	var ok bool
+	if p.timeField != "" {
+		value, ok = lookupJSON(object, p.timeField)
+	} else {
+		for _, field := range jsonTimeFields {
+			value, ok = lookupJSON(object, field)
+			if ok {
+				break
+			}
+		}
+	}
+	if !ok {
+		return time.Time{}, fmt.Errorf("line '%s': no time field: %w", l, errInvalidLogFormat)
+	}
+
+	switch v := value.(type) {
+	case json.Number:
+		return parseJSONNumberTime(v, p.timeFormat)
+	case string:
+		switch p.timeFormat {
+		case JSONTimeAuto, JSONTimeRFC3339:
+			return time.Parse(time.RFC3339Nano, v)
+		case JSONTimeUnix, JSONTimeUnixMilli, JSONTimeUnixMicro, JSONTimeUnixNano:
+			return parseJSONNumberTime(json.Number(v), p.timeFormat)
+		default:
+			return time.Parse(p.timeFormat, v)
+		}
+	default:
+		return time.Time{}, fmt.Errorf("line '%s': invalid time field: %w", l, errInvalidLogFormat)
+	}
+}
+
+// parseJSONNumberTime converts a unix timestamp into time.Time,
+// the auto format guesses the unit by the magnitude of the timestamp
+func parseJSONNumberTime(n json.Number, format string) (time.Time, error) {
+	unit := time.Second
+	switch format {
+	case JSONTimeUnixMilli:
+		unit = time.Millisecond
+	case JSONTimeUnixMicro:
+		unit = time.Microsecond
+	case JSONTimeUnixNano:
+		unit = time.Nanosecond
+	case JSONTimeAuto:
+		f, err := n.Float64()
+		if err != nil {
+			return time.Time{}, err
+		}
+		switch magnitude := math.Abs(f); {
+		case magnitude >= 1e17:
+			unit = time.Nanosecond
+		case magnitude >= 1e14:
+			unit = time.Microsecond
+		case magnitude >= 1e11:
+			unit = time.Millisecond
+		}
+	}
+	// the exponent form, i.e. 1.6463718e9, is parsed as a float, losing the precision below the microsecond
+	if strings.ContainsAny(n.String(), "eE") {
+		f, err := n.Float64()
+		if err != nil {
+			return time.Time{}, fmt.Errorf("parsing time %q: %w", n.String(), errInvalidLogFormat)
+		}
+		return time.Unix(0, int64(math.Round(f*float64(unit)))).UTC(), nil
+	}
+	return unixTime(unit)(n.String())
+}
+
+// lookupJSON looks up a dot separated path inside a JSON object.
+// arrays resolve to their first element, i.e. caddy's request headers: {"User-Agent": ["curl/7.79.1"]}
+func lookupJSON(object map[string]interface{}, path string) (interface{}, bool) {
+	var value interface{} = object
+	for _, key := range strings.Split(path, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		value, ok = m[key]
+		if !ok {
+			return nil, false
+		}
+	}
+	if values, ok := value.([]interface{}); ok {
+		if len(values) == 0 {
+			return nil, false
+		}
+		value = values[0]
+	}
+	if value == nil {
+		return nil, false
+	}
+	return value, true
+}
+
+// jsonString converts a scalar JSON value to its string representation
+func jsonString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		var buf bytes.Buffer
+		_ = json.NewEncoder(&buf).Encode(v)
+		return strings.TrimSpace(buf.String())
+	}
+}
+
+func isJSONTimeField(key string) bool {
+	for _, field := range jsonTimeFields {
+		if key == field {
+			return true
+		}
+	}
+	return false
+}
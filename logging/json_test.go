@@ -0,0 +1,203 @@
+package logging
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type jsonSuite struct {
+	suite.Suite
+}
+
+func (s *jsonSuite) Test_ParseTime_Success() {
+	expectedTime, err := time.Parse(time.RFC3339, "2022-03-04T05:30:00Z")
+	s.Require().NoError(err)
+	tests := []struct {
+		name       string
+		timeField  string
+		timeFormat string
+		log        string
+	}{
+		{
+			name:       "RFC3339 Default Field",
+			timeFormat: JSONTimeAuto,
+			log:        `{"time":"2022-03-04T05:30:00Z","msg":"ok"}`,
+		},
+		{
+			name:       "Unix Seconds Auto",
+			timeFormat: JSONTimeAuto,
+			log:        `{"ts":1646371800.0,"msg":"ok"}`,
+		},
+		{
+			name:       "Unix Seconds Exponent Auto",
+			timeFormat: JSONTimeAuto,
+			log:        `{"ts":1.6463718e9,"msg":"ok"}`,
+		},
+		{
+			name:       "Unix Millis Exponent",
+			timeField:  "ts",
+			timeFormat: JSONTimeUnixMilli,
+			log:        `{"ts":1.6463718E+12}`,
+		},
+		{
+			name:       "Unix Millis Auto",
+			timeFormat: JSONTimeAuto,
+			log:        `{"timestamp":1646371800000}`,
+		},
+		{
+			name:       "Unix Nanos Auto",
+			timeFormat: JSONTimeAuto,
+			log:        `{"timestamp":1646371800000000000}`,
+		},
+		{
+			name:       "Nested Field Unix Millis",
+			timeField:  "request.started",
+			timeFormat: JSONTimeUnixMilli,
+			log:        `{"request":{"started":"1646371800000"}}`,
+		},
+		{
+			name:       "Custom Layout",
+			timeField:  "date",
+			timeFormat: "2006-01-02 15:04:05",
+			log:        `{"date":"2022-03-04 05:30:00"}`,
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			parser := NewJSONParser(test.timeField, test.timeFormat)
+
+			t, err := parser.ParseTime(test.log)
+
+			s.NoError(err)
+			s.True(expectedTime.Equal(t), t.String())
+		})
+	}
+}
+
+func (s *jsonSuite) Test_ParseTime_Error() {
+	tests := []struct {
+		name        string
+		log         string
+		expectedErr string
+	}{
+		{
+			name:        "Invalid JSON",
+			log:         `{"time":`,
+			expectedErr: `line '{"time":': invalid log format`,
+		},
+		{
+			name:        "No Time Field",
+			log:         `{"msg":"ok"}`,
+			expectedErr: `line '{"msg":"ok"}': no time field: invalid log format`,
+		},
+		{
+			name:        "Invalid Time Field",
+			log:         `{"time":true}`,
+			expectedErr: `line '{"time":true}': invalid time field: invalid log format`,
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			parser := NewJSONParser("", "")
+
+			t, err := parser.ParseTime(test.log)
+
+			s.EqualError(err, test.expectedErr)
+			s.True(t.IsZero())
+		})
+	}
+}
+
+func (s *jsonSuite) Test_ParseEntry_Success() {
+	logTime, err := time.Parse(time.RFC3339, "2022-03-04T05:30:00Z")
+	s.Require().NoError(err)
+	tests := []struct {
+		name          string
+		log           string
+		expectedEntry Entry
+	}{
+		{
+			name: "Caddy",
+			log:  `{"level":"info","ts":1646371800,"logger":"http.log.access","request":{"remote_ip":"10.0.0.1","proto":"HTTP/2.0","method":"GET","uri":"/api/users","headers":{"User-Agent":["curl/7.79.1"]}},"duration":0.25,"size":612,"status":200}`,
+			expectedEntry: Entry{
+				RemoteHost: "10.0.0.1",
+				Time:       logTime,
+				Method:     "GET",
+				Path:       "/api/users",
+				Protocol:   "HTTP/2.0",
+				Status:     200,
+				Bytes:      612,
+				UserAgent:  "curl/7.79.1",
+				Duration:   250 * time.Millisecond,
+				Fields:     map[string]string{"level": "info", "logger": "http.log.access"},
+			},
+		},
+		{
+			name: "Traefik",
+			log:  `{"ClientHost":"10.0.0.2","DownstreamContentSize":42,"DownstreamStatus":404,"Duration":1500000,"RequestMethod":"POST","RequestPath":"/login","RequestProtocol":"HTTP/1.1","StartUTC":"2022-03-04T05:30:00Z"}`,
+			expectedEntry: Entry{
+				RemoteHost: "10.0.0.2",
+				Time:       logTime,
+				Method:     "POST",
+				Path:       "/login",
+				Protocol:   "HTTP/1.1",
+				Status:     404,
+				Bytes:      42,
+				Duration:   1500 * time.Microsecond,
+			},
+		},
+		{
+			name: "Application",
+			log:  `{"time":"2022-03-04T05:30:00Z","method":"DELETE","path":"/api/users/1","status":204,"duration":"3ms","request_id":"abc-123"}`,
+			expectedEntry: Entry{
+				Time:     logTime,
+				Method:   "DELETE",
+				Path:     "/api/users/1",
+				Status:   204,
+				Duration: 3 * time.Millisecond,
+				Fields:   map[string]string{"request_id": "abc-123"},
+			},
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			parser := NewJSONParser("", JSONTimeAuto)
+
+			entry, err := parser.ParseEntry(test.log)
+
+			s.NoError(err)
+			s.True(test.expectedEntry.Time.Equal(entry.Time))
+			entry.Time = test.expectedEntry.Time
+			s.Equal(test.expectedEntry, entry)
+		})
+	}
+}
+
+func (s *jsonSuite) Test_IndexTime_JSONLines() {
+	logs := `{"ts":1646371800,"status":200}
+{"ts":1646371860,"status":200}
+{"ts":1646371920,"status":500}
+{"ts":1646371980,"status":200}
+`
+	f, err := os.CreateTemp(s.T().TempDir(), "*-http.jsonl")
+	s.Require().NoError(err)
+	defer func() { s.Require().NoError(f.Close()) }()
+	_, err = f.WriteString(logs)
+	s.Require().NoError(err)
+	file := NewFile(f, NewJSONParser("ts", JSONTimeUnix))
+
+	offset, err := file.IndexTime(time.Unix(1646371900, 0))
+	s.NoError(err)
+	s.Equal(int64(62), offset)
+
+	offset, err = file.IndexTimeEnd(time.Unix(1646371920, 0))
+	s.NoError(err)
+	s.Equal(int64(93), offset)
+}
+
+func TestJSON(t *testing.T) {
+	suite.Run(t, new(jsonSuite))
+}
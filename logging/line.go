@@ -0,0 +1,149 @@
+package logging
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// DefaultMaxLineSize is the default maximum size of a log line
+const DefaultMaxLineSize = 1024 * 1024 // 1MB
+
+// Policies for the log lines longer than the maximum line size
+const (
+	// LongLinesFail stops reading with an error at the first line that is too long
+	LongLinesFail = "fail"
+	// LongLinesSkip skips the lines that are too long, logging a warning for each of them
+	LongLinesSkip = "skip"
+)
+
+var (
+	errLineTooLong   = errors.New("line too long")
+	errUnknownPolicy = errors.New("unknown policy")
+)
+
+// newLineReader creates a line reader starting at the given offset of the underlying reader,
+// reading stops with the context error as soon as the context is done.
+// tooLong decides what happens with the lines longer than max: the line is skipped when it returns nil,
+// otherwise the reading fails with the returned error. A nil tooLong fails with errLineTooLong
+func newLineReader(ctx context.Context, reader io.Reader, offset int64, max int, tooLong func(err error) error) *lineReader {
+	if max <= 0 {
+		max = DefaultMaxLineSize
+	}
+	return &lineReader{
+		ctx:     ctx,
+		reader:  bufio.NewReader(reader),
+		max:     max,
+		offset:  offset,
+		tooLong: tooLong,
+	}
+}
+
+// lineReader reads lines of any length, unlike bufio.Scanner which stops at 64KB lines,
+// keeping at most max bytes of a line in memory: longer lines are dropped as they are read
+type lineReader struct {
+	ctx     context.Context
+	reader  *bufio.Reader
+	max     int
+	tooLong func(err error) error
+	// offset is the offset of the next byte to be read
+	offset int64
+	// follow keeps an incomplete last line till the rest of it is written, instead of returning it
+	follow bool
+
+	// line is the line being read (dropped once it's too long), start is its offset and size its size so far
+	line  []byte
+	start int64
+	size  int
+}
+
+// next returns the next line without its line ending, io.EOF is returned once there are no lines left
+func (l *lineReader) next() (string, error) {
+	for {
+		if err := l.ctx.Err(); err != nil {
+			return "", err
+		}
+
+		fragment, err := l.reader.ReadSlice('\n')
+		if l.size == 0 {
+			l.start = l.offset
+		}
+		l.offset += int64(len(fragment))
+		l.size += len(fragment)
+		// leave room for the line ending
+		if l.size <= l.max+2 {
+			l.line = append(l.line, fragment...)
+		}
+
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err == io.EOF && (l.follow || l.size == 0) {
+			return "", io.EOF
+		}
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+
+		line := strings.TrimRight(string(l.line), "\r\n")
+		tooLong := l.size > l.max+2 || len(line) > l.max
+		l.line, l.size = l.line[:0], 0
+		if !tooLong {
+			return line, nil
+		}
+
+		err = fmt.Errorf("offset %d: longer than %d bytes: %w", l.start, l.max, errLineTooLong)
+		if l.tooLong == nil {
+			return "", err
+		}
+		err = l.tooLong(err)
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+// reset starts reading the lines of a new reader at the given offset, dropping the line being read
+func (l *lineReader) reset(reader io.Reader, offset int64) {
+	l.reader.Reset(reader)
+	l.offset = offset
+	l.line, l.size = l.line[:0], 0
+}
+
+// lines creates a line reader for the given log file applying the configured long lines policy
+func (r *Reader) lines(ctx context.Context, reader io.Reader, name string, offset int64) *lineReader {
+	return newLineReader(ctx, reader, offset, r.cfg.MaxLineSize, r.tooLong(name))
+}
+
+// tooLong applies the configured long lines policy to the lines of the given log file
+func (r *Reader) tooLong(name string) func(err error) error {
+	return func(err error) error {
+		if r.cfg.LongLines == LongLinesSkip {
+			r.warnf("skipping line of '%s': %v", name, err)
+			atomic.AddInt64(&r.skipped.TooLong, 1)
+			return nil
+		}
+		return fmt.Errorf("file '%s': %w", name, err)
+	}
+}
+
+// newFile wraps the given log file using the configured parser, maximum line size and policies
+func (r *Reader) newFile(f *os.File) *File {
+	file := NewFile(f, r.parser)
+	file.maxLineSize = r.maxLineSize()
+	file.skipLongLines = r.cfg.LongLines == LongLinesSkip
+	file.skipMalformed = r.skipMalformed()
+	return file
+}
+
+func (r *Reader) maxLineSize() int {
+	if r.cfg.MaxLineSize <= 0 {
+		return DefaultMaxLineSize
+	}
+	return r.cfg.MaxLineSize
+}
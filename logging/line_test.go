@@ -0,0 +1,102 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type lineSuite struct {
+	suite.Suite
+}
+
+func (s *lineSuite) Test_next_LongLines() {
+	long := strings.Repeat("a", 100*1024)
+	tooLong := strings.Repeat("b", 200*1024)
+	tests := []struct {
+		name          string
+		input         string
+		tooLong       func(err error) error
+		expectedLines []string
+		expectedError string
+	}{
+		{
+			name:          "Longer Than Scanner Limit",
+			input:         "first\r\n" + long + "\nlast",
+			expectedLines: []string{"first", long, "last"},
+		},
+		{
+			name:          "Too Long",
+			input:         "first\n" + tooLong + "\nlast\n",
+			expectedLines: []string{"first"},
+			expectedError: "offset 6: longer than 131072 bytes: line too long",
+		},
+		{
+			name:  "Too Long Skipped",
+			input: "first\n" + tooLong + "\n" + tooLong + "\nlast\n",
+			tooLong: func(err error) error {
+				return nil
+			},
+			expectedLines: []string{"first", "last"},
+		},
+		{
+			name:  "Too Long Last Line",
+			input: "first\n" + tooLong,
+			tooLong: func(err error) error {
+				return errors.New("too long")
+			},
+			expectedLines: []string{"first"},
+			expectedError: "too long",
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			lines := newLineReader(context.Background(), strings.NewReader(test.input), 0, 128*1024, test.tooLong)
+
+			var actual []string
+			var err error
+			for {
+				var line string
+				line, err = lines.next()
+				if err != nil {
+					break
+				}
+				actual = append(actual, line)
+			}
+
+			s.Equal(test.expectedLines, actual)
+			if test.expectedError == "" {
+				s.Equal(io.EOF, err)
+			} else {
+				s.EqualError(err, test.expectedError)
+			}
+		})
+	}
+}
+
+func (s *lineSuite) Test_next_Follow() {
+	lines := newLineReader(context.Background(), strings.NewReader("first\nsec"), 0, 0, nil)
+	lines.follow = true
+
+	line, err := lines.next()
+	s.NoError(err)
+	s.Equal("first", line)
+	_, err = lines.next()
+	s.Equal(io.EOF, err)
+	s.Equal(int64(9), lines.offset)
+
+	// the rest of the incomplete line is written later on
+	lines.reader.Reset(strings.NewReader("ond\n"))
+	line, err = lines.next()
+	s.NoError(err)
+	s.Equal("second", line)
+	s.Equal(int64(13), lines.offset)
+}
+
+func TestLine(t *testing.T) {
+	suite.Run(t, new(lineSuite))
+}
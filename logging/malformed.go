@@ -0,0 +1,65 @@
+package logging
+
+import (
+	"sync/atomic"
+)
+
+// Policies for the malformed log lines, the lines that can't be parsed, i.e. partially written lines or binary junk
+const (
+	// MalformedFail stops reading with an error at the first malformed line
+	MalformedFail = "fail"
+	// MalformedSkip skips the malformed lines, the binary search probes the neighbouring lines instead
+	MalformedSkip = "skip"
+	// MalformedQuarantine skips the malformed lines the same way, writing them to ReaderConfig.Quarantine
+	MalformedQuarantine = "quarantine"
+)
+
+// Skipped represents the summary of the lines skipped while reading
+type Skipped struct {
+	// Malformed is the number of lines that could not be parsed
+	Malformed int64
+	// TooLong is the number of lines longer than the maximum line size
+	TooLong int64
+}
+
+// Skipped returns the summary of the lines skipped by the last read
+func (r *Reader) Skipped() Skipped {
+	return Skipped{
+		Malformed: atomic.LoadInt64(&r.skipped.Malformed),
+		TooLong:   atomic.LoadInt64(&r.skipped.TooLong),
+	}
+}
+
+// malformed applies the malformed lines policy to a line that could not be parsed,
+// reading goes on when nil is returned
+func (r *Reader) malformed(line string, err error) error {
+	switch r.cfg.Malformed {
+	case MalformedSkip:
+	case MalformedQuarantine:
+		_, err = r.cfg.Quarantine.Write([]byte(line + "\n"))
+		if err != nil {
+			return err
+		}
+	default:
+		return err
+	}
+
+	atomic.AddInt64(&r.skipped.Malformed, 1)
+	return nil
+}
+
+// summarize logs the summary of the skipped lines, if any
+func (r *Reader) summarize() {
+	skipped := r.Skipped()
+	if skipped.Malformed > 0 {
+		r.warnf("skipped %d malformed lines", skipped.Malformed)
+	}
+	if skipped.TooLong > 0 {
+		r.warnf("skipped %d lines longer than %d bytes", skipped.TooLong, r.maxLineSize())
+	}
+}
+
+// skipMalformed tells whether the malformed lines are skipped instead of failing the read
+func (r *Reader) skipMalformed() bool {
+	return r.cfg.Malformed == MalformedSkip || r.cfg.Malformed == MalformedQuarantine
+}
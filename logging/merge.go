@@ -0,0 +1,183 @@
+package logging
+
+import (
+	"container/heap"
+	"context"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// mergeSource represents a log file taking part in a k-way merge, positioned at its next log
+type mergeSource struct {
+	index int
+	name  string
+	// compressed files have no meaningful offsets
+	compressed bool
+	lines      *lineReader
+	line       Line
+	time       time.Time
+}
+
+// mergeHeap is a min heap of merge sources ordered by the time of their next log,
+// sources with logs at the same time keep the order of their files
+type mergeHeap []*mergeSource
+
+func (h mergeHeap) Len() int { return len(h) }
+
+func (h mergeHeap) Less(i, j int) bool {
+	if h[i].time.Equal(h[j].time) {
+		return h[i].index < h[j].index
+	}
+	return h[i].time.Before(h[j].time)
+}
+
+func (h mergeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *mergeHeap) Push(x interface{}) { *h = append(*h, x.(*mergeSource)) }
+
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	source := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return source
+}
+
+// merge reads the logs of the time window out of all the log files, which may overlap in time,
+// i.e. the logs of multiple hosts behind a load balancer, and emits them globally ordered by time.
+// Each file is searched for the time window, then the files are merged using a min heap (k-way merge)
+func (r *Reader) merge(ctx context.Context, emit func(line Line) error) error {
+	from, to := r.window()
+	h := &mergeHeap{}
+	for i, fi := range r.filesInfo {
+		if fi.endsBefore(from) {
+			continue
+		}
+
+		f, err := os.Open(path.Join(r.cfg.Directory, fi.name))
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+
+		reader, offset, err := r.mergeReader(ctx, f, from, to)
+		if err != nil {
+			return err
+		}
+		compressed := offset < 0
+		if compressed {
+			offset = 0
+		}
+		source := &mergeSource{
+			index:      i,
+			name:       fi.name,
+			compressed: compressed,
+			lines:      r.lines(ctx, reader, fi.name, offset),
+		}
+		ok, err := r.next(source, from, to)
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Push(h, source)
+		}
+	}
+
+	for h.Len() > 0 {
+		source := (*h)[0]
+		err := emit(source.line)
+		if err != nil {
+			return err
+		}
+
+		ok, err := r.next(source, from, to)
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+		}
+	}
+	return nil
+}
+
+// mergeReader returns a reader of the logs inside the file starting at the beginning of the time window, alongside its offset.
+// Plain files are searched for the time window, compressed files are scanned from the beginning and their offset is -1
+func (r *Reader) mergeReader(ctx context.Context, f *os.File, from, to time.Time) (io.Reader, int64, error) {
+	decompressed, compressed, err := decompress(f)
+	if err != nil {
+		return nil, 0, err
+	}
+	if compressed {
+		archive, ok, err := seekArchive(f, from)
+		if err != nil {
+			return nil, 0, err
+		}
+		if ok {
+			return archive, -1, nil
+		}
+		return decompressed, -1, nil
+	}
+
+	file := r.newFile(f)
+	end, err := r.windowEnd(ctx, file, to)
+	if err != nil {
+		return nil, 0, err
+	}
+	offset, err := file.indexTime(ctx, from)
+	if err != nil {
+		return nil, 0, err
+	}
+	if offset < 0 || offset >= end {
+		return strings.NewReader(""), 0, nil
+	}
+
+	_, err = f.Seek(offset, io.SeekStart)
+	if err != nil {
+		return nil, 0, err
+	}
+	return io.LimitReader(f, end-offset), offset, nil
+}
+
+// next advances the merge source to its next log within the time window.
+// ok == false -> there are no more logs inside the time window
+func (r *Reader) next(source *mergeSource, from, to time.Time) (ok bool, err error) {
+	for {
+		line, err := source.lines.next()
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		logTime, err := r.parser.ParseTime(line)
+		if err != nil {
+			err = r.malformed(line, err)
+			if err != nil {
+				return false, err
+			}
+			continue
+		}
+		if logTime.Before(from) {
+			continue
+		}
+		if !to.IsZero() && logTime.After(to) {
+			return false, nil
+		}
+
+		source.line = Line{Text: line, File: source.name, Offset: source.lines.start}
+		if source.compressed {
+			source.line.Offset = -1
+		}
+		source.time = logTime
+		return true, nil
+	}
+}
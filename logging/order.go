@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"time"
+)
+
+// File orders, deciding the order the log files are read in
+const (
+	// OrderModTime orders the log files by their modification time
+	OrderModTime = "mtime"
+	// OrderContent orders the log files by the time of their first and last logs,
+	// useful when the modification times can't be trusted, i.e. after cp, rsync without -t or a backup restore
+	OrderContent = "content"
+)
+
+var errUnknownOrder = errors.New("unknown order")
+
+// orderFiles sorts the log files using the configured order
+// and sets the time each log file ends at, used to find the file the time window begins in
+func (r *Reader) orderFiles() error {
+	switch r.cfg.Order {
+	case "", OrderModTime:
+		sort.SliceStable(r.filesInfo, func(i, j int) bool {
+			return r.filesInfo[i].modTime.Before(r.filesInfo[j].modTime)
+		})
+		for i := range r.filesInfo {
+			r.filesInfo[i].last = r.filesInfo[i].modTime
+		}
+		return nil
+	case OrderContent:
+	default:
+		return fmt.Errorf("order '%s': %w", r.cfg.Order, errUnknownOrder)
+	}
+
+	for i, fi := range r.filesInfo {
+		first, last, err := r.timeRange(fi)
+		if err != nil {
+			return fmt.Errorf("file '%s': %w", fi.name, err)
+		}
+		r.filesInfo[i].first = first
+		r.filesInfo[i].last = last
+	}
+	sort.SliceStable(r.filesInfo, func(i, j int) bool {
+		return r.filesInfo[i].first.Before(r.filesInfo[j].first)
+	})
+
+	for i := range r.filesInfo {
+		fi := &r.filesInfo[i]
+		// the last log of a compressed file is unknown, but it can't be newer than the first log of the next file
+		if fi.last.IsZero() && i+1 < len(r.filesInfo) {
+			fi.last = r.filesInfo[i+1].first
+		}
+
+		if !fi.last.IsZero() && fi.modTime.Before(fi.last) {
+			r.warnf("file '%s' was modified at %v, before its last log at %v", fi.name, fi.modTime, fi.last)
+		}
+		if i > 0 && fi.modTime.Before(r.filesInfo[i-1].modTime) {
+			r.warnf("file '%s' is older than '%s' by modification time, but newer by content", fi.name, r.filesInfo[i-1].name)
+		}
+	}
+	return nil
+}
+
+// timeRange returns the time of the first and the last log of a log file.
+// Compressed files can't be read from the end, so only the time of their first log is returned
+func (r *Reader) timeRange(fi fileInfo) (first, last time.Time, err error) {
+	f, err := os.Open(path.Join(r.cfg.Directory, fi.name))
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	defer func() { _ = f.Close() }()
+
+	decompressed, compressed, err := decompress(f)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if compressed {
+		first, err := firstLogTime(r.lines(context.Background(), decompressed, fi.name, 0), r.parser, r.skipMalformed())
+		return first, time.Time{}, err
+	}
+	return r.newFile(f).TimeRange()
+}
+
+// endsBefore checks whether all the logs inside the log file are older than the given time.
+// Files whose last log is unknown are never considered older
+func (fi fileInfo) endsBefore(t time.Time) bool {
+	return !fi.last.IsZero() && fi.last.Before(t)
+}
@@ -0,0 +1,160 @@
+package logging
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Output formats of the parsed log entries
+const (
+	OutputRaw    = "raw"
+	OutputJSON   = "json"
+	OutputNDJSON = "ndjson"
+	OutputCSV    = "csv"
+)
+
+var errUnknownOutput = errors.New("unknown output")
+
+// csvHeader are the columns of the CSV output, custom fields are written as key=value pairs inside the fields column
+var csvHeader = []string{"time", "remote_host", "ident", "user", "method", "path", "protocol", "status", "bytes", "referer", "user_agent", "duration", "fields"}
+
+// EntryWriter writes parsed log entries in a structured format.
+// Close has to be called once all the entries were written, to finish the output (i.e. close a JSON array)
+type EntryWriter interface {
+	Write(entry Entry) error
+	Close() error
+}
+
+// NewEntryWriter creates an entry writer for one of the output formats: json (one JSON array), ndjson (JSON lines) or csv.
+// The raw output has no entry writer, since it's the log line itself, use Reader.Read instead
+func NewEntryWriter(w io.Writer, format string) (EntryWriter, error) {
+	switch format {
+	case OutputJSON:
+		return &jsonWriter{w: w, array: true}, nil
+	case OutputNDJSON:
+		return &jsonWriter{w: w}, nil
+	case OutputCSV:
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	}
+	return nil, fmt.Errorf("output '%s': %w", format, errUnknownOutput)
+}
+
+// NewTemplateWriter creates an entry writer executing a Go template for every entry, i.e.
+// {{.Time}} {{.Status}} {{.Path}} {{index .Fields "X-Request-Id"}}
+// A new line is written after every entry, unless the template already ends with one
+func NewTemplateWriter(w io.Writer, text string) (EntryWriter, error) {
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	tmpl, err := template.New("entry").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return &templateWriter{w: w, tmpl: tmpl}, nil
+}
+
+type jsonWriter struct {
+	w       io.Writer
+	array   bool
+	written bool
+}
+
+func (j *jsonWriter) Write(entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	// JSON arrays have one entry per line as well, separated by commas
+	prefix, suffix := "", "\n"
+	if j.array {
+		prefix, suffix = ",\n", ""
+		if !j.written {
+			prefix = "[\n"
+		}
+	}
+	j.written = true
+	_, err = io.WriteString(j.w, prefix+string(data)+suffix)
+	return err
+}
+
+func (j *jsonWriter) Close() error {
+	if !j.array {
+		return nil
+	}
+	if !j.written {
+		_, err := io.WriteString(j.w, "[]\n")
+		return err
+	}
+	_, err := io.WriteString(j.w, "\n]\n")
+	return err
+}
+
+type csvWriter struct {
+	w       *csv.Writer
+	written bool
+}
+
+func (c *csvWriter) Write(entry Entry) error {
+	if !c.written {
+		c.written = true
+		err := c.w.Write(csvHeader)
+		if err != nil {
+			return err
+		}
+	}
+
+	fields := make([]string, 0, len(entry.Fields))
+	for key, value := range entry.Fields {
+		fields = append(fields, key+"="+value)
+	}
+	sort.Strings(fields)
+	record := []string{
+		entry.Time.Format(time.RFC3339),
+		entry.RemoteHost,
+		entry.Ident,
+		entry.User,
+		entry.Method,
+		entry.Path,
+		entry.Protocol,
+		strconv.Itoa(entry.Status),
+		strconv.FormatInt(entry.Bytes, 10),
+		entry.Referer,
+		entry.UserAgent,
+		entry.Duration.String(),
+		strings.Join(fields, " "),
+	}
+	err := c.w.Write(record)
+	if err != nil {
+		return err
+	}
+	// flush every record, so followed logs show up right away
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type templateWriter struct {
+	w    io.Writer
+	tmpl *template.Template
+}
+
+func (t *templateWriter) Write(entry Entry) error {
+	return t.tmpl.Execute(t.w, entry)
+}
+
+func (t *templateWriter) Close() error {
+	return nil
+}
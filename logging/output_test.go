@@ -0,0 +1,131 @@
+package logging
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type outputSuite struct {
+	suite.Suite
+	entries []Entry
+}
+
+func (s *outputSuite) SetupSuite() {
+	logTime, err := time.Parse(dateTimeFormat, "04/Mar/2022:05:30:00 +0000")
+	s.Require().NoError(err)
+	s.entries = []Entry{
+		{
+			RemoteHost: "127.0.0.1",
+			User:       "frank",
+			Time:       logTime,
+			Method:     "GET",
+			Path:       "/api/endpoint",
+			Protocol:   "HTTP/1.0",
+			Status:     500,
+			Bytes:      123,
+		},
+		{
+			RemoteHost: "10.0.0.7",
+			Time:       logTime.Add(time.Second),
+			Method:     "POST",
+			Path:       "/api/users",
+			Protocol:   "HTTP/1.1",
+			Status:     201,
+			Bytes:      2326,
+			UserAgent:  `Mozilla/5.0 "quoted", with comma`,
+			Duration:   1500 * time.Microsecond,
+			Fields:     map[string]string{"X-Request-Id": "abc-123", "port": "443"},
+		},
+	}
+}
+
+func (s *outputSuite) Test_NewEntryWriter_Success() {
+	tests := []struct {
+		name           string
+		format         string
+		entries        []Entry
+		expectedOutput string
+	}{
+		{
+			name:    "JSON",
+			format:  OutputJSON,
+			entries: s.entries,
+			expectedOutput: `[
+{"remote_host":"127.0.0.1","ident":"","user":"frank","time":"2022-03-04T05:30:00Z","method":"GET","path":"/api/endpoint","protocol":"HTTP/1.0","status":500,"bytes":123,"referer":"","user_agent":"","duration":0},
+{"remote_host":"10.0.0.7","ident":"","user":"","time":"2022-03-04T05:30:01Z","method":"POST","path":"/api/users","protocol":"HTTP/1.1","status":201,"bytes":2326,"referer":"","user_agent":"Mozilla/5.0 \"quoted\", with comma","duration":1500000,"fields":{"X-Request-Id":"abc-123","port":"443"}}
+]
+`,
+		},
+		{
+			name:           "Empty JSON",
+			format:         OutputJSON,
+			expectedOutput: "[]\n",
+		},
+		{
+			name:    "NDJSON",
+			format:  OutputNDJSON,
+			entries: s.entries,
+			expectedOutput: `{"remote_host":"127.0.0.1","ident":"","user":"frank","time":"2022-03-04T05:30:00Z","method":"GET","path":"/api/endpoint","protocol":"HTTP/1.0","status":500,"bytes":123,"referer":"","user_agent":"","duration":0}
+{"remote_host":"10.0.0.7","ident":"","user":"","time":"2022-03-04T05:30:01Z","method":"POST","path":"/api/users","protocol":"HTTP/1.1","status":201,"bytes":2326,"referer":"","user_agent":"Mozilla/5.0 \"quoted\", with comma","duration":1500000,"fields":{"X-Request-Id":"abc-123","port":"443"}}
+`,
+		},
+		{
+			name:    "CSV",
+			format:  OutputCSV,
+			entries: s.entries,
+			expectedOutput: `time,remote_host,ident,user,method,path,protocol,status,bytes,referer,user_agent,duration,fields
+2022-03-04T05:30:00Z,127.0.0.1,,frank,GET,/api/endpoint,HTTP/1.0,500,123,,,0s,
+2022-03-04T05:30:01Z,10.0.0.7,,,POST,/api/users,HTTP/1.1,201,2326,,"Mozilla/5.0 ""quoted"", with comma",1.5ms,X-Request-Id=abc-123 port=443
+`,
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			buf := &bytes.Buffer{}
+			writer, err := NewEntryWriter(buf, test.format)
+			s.Require().NoError(err)
+
+			for _, entry := range test.entries {
+				s.Require().NoError(writer.Write(entry))
+			}
+			s.Require().NoError(writer.Close())
+
+			s.Equal(test.expectedOutput, buf.String())
+		})
+	}
+}
+
+func (s *outputSuite) Test_NewEntryWriter_Error() {
+	writer, err := NewEntryWriter(&bytes.Buffer{}, "xml")
+
+	s.EqualError(err, "output 'xml': unknown output")
+	s.Nil(writer)
+}
+
+func (s *outputSuite) Test_NewTemplateWriter_Success() {
+	buf := &bytes.Buffer{}
+	writer, err := NewTemplateWriter(buf, `{{.Time.Format "15:04:05"}} {{.Status}} {{.Path}} {{index .Fields "X-Request-Id"}}`)
+	s.Require().NoError(err)
+
+	for _, entry := range s.entries {
+		s.Require().NoError(writer.Write(entry))
+	}
+	s.Require().NoError(writer.Close())
+
+	s.Equal("05:30:00 500 /api/endpoint \n05:30:01 201 /api/users abc-123\n", buf.String())
+}
+
+func (s *outputSuite) Test_NewTemplateWriter_Error() {
+	writer, err := NewTemplateWriter(&bytes.Buffer{}, "{{.Status")
+
+	s.Error(err)
+	s.Contains(err.Error(), "unclosed action")
+	s.Nil(writer)
+}
+
+func TestOutput(t *testing.T) {
+	suite.Run(t, new(outputSuite))
+}
@@ -0,0 +1,205 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// defaultChunkSize is the size of the chunks the logs of a file are split into for the workers
+const defaultChunkSize = 4 * 1024 * 1024 // 4MB
+
+// parsedLine represents a log line parsed by a worker, err is errSkipped when it was filtered out
+type parsedLine struct {
+	entry Entry
+	err   error
+}
+
+// span represents a line aligned byte range of a log file
+type span struct {
+	start int64
+	end   int64
+}
+
+// chunkResult represents the lines of a chunk, parsed when needed, or the error that stopped reading it
+type chunkResult struct {
+	lines []Line
+	err   error
+}
+
+// Workers returns the number of workers reading the logs, at least 1
+func (r *Reader) Workers() int {
+	return r.workers()
+}
+
+func (r *Reader) workers() int {
+	if r.cfg.Workers < 1 {
+		return 1
+	}
+	return r.cfg.Workers
+}
+
+// AggregateEntries reads the logs the same way ReadEntries does, except that the workers call fn themselves
+// with the logs of their chunks, so fn is called in parallel and out of order by the workers, numbered from 0 to Workers()-1.
+// The calls of a worker never overlap, so fn can add the logs to one partial aggregate per worker,
+// i.e. analytics.Stats, merging them once the reading is done. The logs read sequentially (compressed files,
+// merged or followed logs) are passed to worker 0, so are all the logs when there is a Limit, since they have to be counted in order
+func (r *Reader) AggregateEntries(ctx context.Context, fn func(worker int, entry Entry) error) error {
+	if r.cfg.Limit <= 0 {
+		r.aggregate = fn
+		defer func() { r.aggregate = nil }()
+	}
+
+	emit := func(line Line) error {
+		entry, err := r.entry(line)
+		if err != nil {
+			return err
+		}
+		return fn(0, entry)
+	}
+	return r.run(ctx, true, emit)
+}
+
+// parallel reads the logs of the file between the start and end offsets using a pool of workers.
+// The range is split into line aligned chunks, which the workers read, parse and filter (or aggregate),
+// while the lines of the chunks are emitted in order. At most 2 chunks per worker are read ahead of the emitted one
+func (r *Reader) parallel(ctx context.Context, emit func(line Line) error, file *File, name string, start, end int64) error {
+	spans, err := file.chunks(start, end, r.chunkSize)
+	if err != nil {
+		return err
+	}
+
+	workers := r.workers()
+	results := make([]chan chunkResult, len(spans))
+	for i := range results {
+		results[i] = make(chan chunkResult, 1)
+	}
+	jobs := make(chan int)
+	ahead := make(chan struct{}, 2*workers)
+
+	// the workers stop as soon as this function returns, they must be done before the file is closed
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(jobs)
+		for i := range spans {
+			select {
+			case ahead <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] <- r.readChunk(ctx, worker, file, name, spans[i])
+			}
+		}(w)
+	}
+
+	for i := range spans {
+		var result chunkResult
+		select {
+		case result = <-results[i]:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		<-ahead
+		if result.err != nil {
+			return result.err
+		}
+
+		for _, line := range result.lines {
+			err := emit(line)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readChunk reads the lines of a chunk, parsing and filtering them when needed, or aggregating them.
+// The lines that were filtered out or aggregated are dropped, except the last line of the chunk,
+// which is kept to keep track of the last line read
+func (r *Reader) readChunk(ctx context.Context, worker int, file *File, name string, chunk span) chunkResult {
+	var result chunkResult
+	var skipped *Line
+	lines := r.lines(ctx, io.NewSectionReader(file, chunk.start, chunk.end-chunk.start), name, chunk.start)
+	for {
+		text, err := lines.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return chunkResult{err: err}
+		}
+
+		line := Line{Text: text, File: name, Offset: lines.start}
+		if r.parseLines {
+			line.parsed = r.parse(text)
+			if line.parsed.err == nil && r.aggregate != nil {
+				err := r.aggregate(worker, line.parsed.entry)
+				if err != nil {
+					return chunkResult{err: err}
+				}
+				line.parsed = &parsedLine{err: errSkipped}
+			}
+			if line.parsed.err == errSkipped {
+				skipped = &line
+				continue
+			}
+		}
+		skipped = nil
+		result.lines = append(result.lines, line)
+	}
+
+	if skipped != nil {
+		result.lines = append(result.lines, *skipped)
+	}
+	return result
+}
+
+// chunks splits the byte range between the start and end offsets into line aligned chunks of about the given size,
+// each chunk ends right after the line its nominal end falls into, which is found using seekLine
+func (file *File) chunks(start, end, size int64) ([]span, error) {
+	var spans []span
+	for start < end {
+		next := start + size
+		if next >= end {
+			spans = append(spans, span{start: start, end: end})
+			break
+		}
+
+		// seeking from the byte before makes the chunk end right there when the next line begins at the nominal end
+		_, err := file.Seek(next-1, io.SeekStart)
+		if err != nil {
+			return nil, err
+		}
+		next, err = file.seekLine(1, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+		if next > end || next <= start {
+			next = end
+		}
+		spans = append(spans, span{start: start, end: next})
+		start = next
+	}
+	return spans, nil
+}
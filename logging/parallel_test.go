@@ -0,0 +1,269 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+func (s *readerSuite) Test_Read_Parallel() {
+	dir := s.T().TempDir()
+	s.createParallelLogs(dir)
+	tests := []struct {
+		name string
+		cfg  ReaderConfig
+	}{
+		{
+			name: "Raw",
+			cfg:  ReaderConfig{Since: 250 * time.Second},
+		},
+		{
+			name: "Filter",
+			cfg:  ReaderConfig{Since: 250 * time.Second, Filter: s.parseFilter("status == 500")},
+		},
+		{
+			name: "Time Window",
+			cfg:  ReaderConfig{From: testStart.Add(30 * time.Second), To: testStart.Add(170 * time.Second)},
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			test.cfg.Directory = dir
+			sequential := s.readParallel(test.cfg, 1)
+
+			parallel := s.readParallel(test.cfg, 4)
+
+			s.NotEmpty(sequential)
+			s.Equal(sequential, parallel)
+		})
+	}
+}
+
+func (s *readerSuite) Test_ReadEntries_Parallel() {
+	dir := s.T().TempDir()
+	s.createParallelLogs(dir)
+	var sequential, parallel []string
+	read := func(workers int, paths *[]string) {
+		reader, err := NewReader(ReaderConfig{
+			Directory: dir,
+			Since:     250 * time.Second,
+			Filter:    s.parseFilter("status == 500"),
+			Workers:   workers,
+		})
+		s.Require().NoError(err)
+		reader.nowFunc = s.parallelNow
+		reader.chunkSize = 512
+
+		err = reader.ReadEntries(context.Background(), func(entry Entry) error {
+			*paths = append(*paths, entry.Path)
+			return nil
+		})
+		s.Require().NoError(err)
+	}
+
+	read(1, &sequential)
+	read(4, &parallel)
+
+	s.Len(sequential, 40)
+	s.Equal(sequential, parallel)
+}
+
+func (s *readerSuite) Test_ReadEntries_ParallelMalformed() {
+	dir := s.T().TempDir()
+	var logs strings.Builder
+	for i := 0; i < 100; i++ {
+		logs.WriteString(testLogs(testStart, i, i+1))
+		if i%10 == 5 {
+			logs.WriteString(fmt.Sprintf("junk %d\n", i))
+		}
+	}
+	s.createLogFile(dir, "http.log", logs.String())
+	quarantine := &bytes.Buffer{}
+	reader, err := NewReader(ReaderConfig{
+		Directory:  dir,
+		From:       testStart,
+		Malformed:  MalformedQuarantine,
+		Quarantine: quarantine,
+		Workers:    4,
+	})
+	s.Require().NoError(err)
+	reader.chunkSize = 512
+	count := 0
+
+	err = reader.ReadEntries(context.Background(), func(entry Entry) error {
+		count++
+		return nil
+	})
+
+	s.NoError(err)
+	s.Equal(100, count)
+	s.Equal(Skipped{Malformed: 10}, reader.Skipped())
+	s.Equal("junk 5\njunk 15\njunk 25\njunk 35\njunk 45\njunk 55\njunk 65\njunk 75\njunk 85\njunk 95\n", quarantine.String())
+}
+
+func (s *readerSuite) Test_AggregateEntries() {
+	dir := s.T().TempDir()
+	s.createParallelLogs(dir)
+	reader, err := NewReader(ReaderConfig{
+		Directory: dir,
+		Since:     250 * time.Second,
+		Filter:    s.parseFilter("status == 500"),
+		Workers:   4,
+	})
+	s.Require().NoError(err)
+	reader.nowFunc = s.parallelNow
+	reader.chunkSize = 512
+	counts := make([]int, reader.Workers())
+	busy := make([]int32, reader.Workers())
+
+	err = reader.AggregateEntries(context.Background(), func(worker int, entry Entry) error {
+		// the calls of a worker never overlap
+		s.True(atomic.CompareAndSwapInt32(&busy[worker], 0, 1), "overlapping calls")
+		counts[worker]++
+		atomic.StoreInt32(&busy[worker], 0)
+		return nil
+	})
+
+	s.NoError(err)
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	s.Equal(40, total)
+	last, err := reader.Checkpoint()
+	s.Require().NoError(err)
+	s.Equal("http-2.log", last.File)
+}
+
+func (s *readerSuite) Test_ReadEntries_ParallelNoLeaks() {
+	dir := s.T().TempDir()
+	s.createParallelLogs(dir)
+	errEmit := errors.New("emit error")
+	tests := []struct {
+		name          string
+		cancel        bool
+		expectedError error
+	}{
+		{
+			name:          "Emit Error",
+			expectedError: errEmit,
+		},
+		{
+			name:   "Canceled",
+			cancel: true,
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			s.assertNoLeaks(func() {
+				reader, err := NewReader(ReaderConfig{Directory: dir, Since: 250 * time.Second, Workers: 4})
+				s.Require().NoError(err)
+				reader.nowFunc = s.parallelNow
+				reader.chunkSize = 512
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+
+				count := 0
+				err = reader.ReadEntries(ctx, func(entry Entry) error {
+					count++
+					if count < 10 {
+						return nil
+					}
+					if test.cancel {
+						cancel()
+						return nil
+					}
+					return errEmit
+				})
+
+				s.Equal(test.expectedError, err)
+			})
+		})
+	}
+}
+
+func (s *readerSuite) Test_File_Chunks() {
+	// every log line is 10 bytes long
+	content := strings.Repeat("123456789\n", 10)
+	f := s.createLogFile(s.T().TempDir(), "http.log", content)
+	file := NewFile(f, NewApacheParser())
+	tests := []struct {
+		name          string
+		start         int64
+		end           int64
+		size          int64
+		expectedSpans []span
+	}{
+		{
+			name:          "Line Aligned",
+			start:         0,
+			end:           100,
+			size:          30,
+			expectedSpans: []span{{0, 30}, {30, 60}, {60, 90}, {90, 100}},
+		},
+		{
+			name:          "Inside Lines",
+			start:         10,
+			end:           95,
+			size:          25,
+			expectedSpans: []span{{10, 40}, {40, 70}, {70, 95}},
+		},
+		{
+			name:          "Single Chunk",
+			start:         20,
+			end:           60,
+			size:          100,
+			expectedSpans: []span{{20, 60}},
+		},
+		{
+			name:  "Empty Range",
+			start: 50,
+			end:   50,
+			size:  10,
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			spans, err := file.chunks(test.start, test.end, test.size)
+
+			s.NoError(err)
+			s.Equal(test.expectedSpans, spans)
+		})
+	}
+}
+
+// readParallel reads the raw logs using the given number of workers and small chunks
+func (s *readerSuite) readParallel(cfg ReaderConfig, workers int) string {
+	cfg.Workers = workers
+	reader, err := NewReader(cfg)
+	s.Require().NoError(err)
+	reader.nowFunc = s.parallelNow
+	reader.chunkSize = 512
+	out := &bytes.Buffer{}
+
+	s.Require().NoError(reader.Read(context.Background(), out))
+	return out.String()
+}
+
+// createParallelLogs creates 2 log files of 100 logs each, a log every second, every fifth log failed
+func (s *readerSuite) createParallelLogs(dir string) {
+	for i := 0; i < 2; i++ {
+		name := path.Join(dir, fmt.Sprintf("http-%d.log", i+1))
+		writeTestLogs(s.T(), name, testLogs(testStart, i*100, (i+1)*100), testStart.Add(time.Duration(i+1)*100*time.Second))
+	}
+}
+
+func (s *readerSuite) parallelNow() time.Time {
+	return testStart.Add(200 * time.Second)
+}
+
+func (s *readerSuite) parseFilter(expr string) *Filter {
+	filter, err := ParseFilter(expr)
+	s.Require().NoError(err)
+	return filter
+}
@@ -0,0 +1,309 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const dateTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+var (
+	errInvalidLogFormat = errors.New("invalid log format")
+	errUnknownParser    = errors.New("unknown parser")
+
+	// commonLogRegEx matches both apache common and combined log formats
+	commonLogRegEx = regexp.MustCompile(fmt.Sprintf(
+		`^(?P<%s>\S+) (?P<%s>\S+) (?P<%s>\S+) \[(?P<%s>[\w:/]+\s[+\-]\d{4})\] "(?P<%s>\S+)\s?(?P<%s>\S+)?\s?(?P<%s>\S+)?" (?P<%s>\d{3}|-) (?P<%s>\d+|-)\s?"?(?P<%s>[^"]*)"?\s?"?(?P<%s>[^"]*)?"?$`,
+		remoteHostGroupName,
+		identGroupName,
+		userGroupName,
+		dateTimeGroupName,
+		methodGroupName,
+		pathGroupName,
+		protocolGroupName,
+		statusGroupName,
+		bytesGroupName,
+		refererGroupName,
+		userAgentGroupName,
+	))
+	// syslogRegEx matches syslog lines using high precision RFC3339 timestamps (rsyslog's default file format)
+	syslogRegEx = regexp.MustCompile(fmt.Sprintf(
+		`^(?P<%s>\d{4}-\d{2}-\d{2}T\S+) (?P<%s>\S+) (?P<%s>[^\s:\[]+)(\[\d+\])?:`,
+		dateTimeGroupName,
+		remoteHostGroupName,
+		identGroupName,
+	))
+
+	parsers = map[string]func() LineParser{
+		"apache":   NewApacheParser,
+		"common":   NewApacheParser,
+		"combined": NewApacheParser,
+		// nginx's default log_format (combined) is the same as apache's combined log format
+		"nginx":  NewApacheParser,
+		"syslog": newSyslogParser,
+		"jsonl":  newDefaultJSONParser,
+	}
+)
+
+// LineParser represents the type responsible for understanding a specific log format.
+// Both File and Reader rely on it to extract the time of each log line
+type LineParser interface {
+	// ParseTime extracts the time a given log line was written at
+	ParseTime(line string) (time.Time, error)
+	// ParseEntry parses a given log line into an Entry
+	ParseEntry(line string) (Entry, error)
+}
+
+// NewApacheParser creates the default line parser for apache common and combined log formats
+// example of apache common log line:
+// 127.0.0.1 user-identifier frank [04/Mar/2022:05:30:00 +0000] "GET /api/endpoint HTTP/1.0" 500 123
+func NewApacheParser() LineParser {
+	return newRegexParser(commonLogRegEx, layoutTime(dateTimeFormat))
+}
+
+func newSyslogParser() LineParser {
+	return newRegexParser(syslogRegEx, layoutTime(time.RFC3339Nano))
+}
+
+// ParserByName returns one of the built-in line parsers by its name
+func ParserByName(name string) (LineParser, error) {
+	newParser, ok := parsers[name]
+	if !ok {
+		return nil, fmt.Errorf("parser '%s', available parsers: %s: %w", name, strings.Join(ParserNames(), ", "), errUnknownParser)
+	}
+	return newParser(), nil
+}
+
+// ParserNames returns the names of all the built-in line parsers
+func ParserNames() []string {
+	names := make([]string, 0, len(parsers))
+	for name := range parsers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewRegexParser creates a line parser out of a regular expression and a time layout.
+// The regular expression must have at least the "datetime" named group which is parsed using the time layout,
+// the other recognized named groups are: host, ident, user, method, path, protocol, status, bytes, referer, agent.
+// Any other named group is stored inside Entry.Fields
+func NewRegexParser(pattern, timeLayout string) (*RegexParser, error) {
+	regEx, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if regEx.SubexpIndex(dateTimeGroupName) < 0 {
+		return nil, fmt.Errorf("pattern '%s' has no '%s' group: %w", pattern, dateTimeGroupName, errInvalidLogFormat)
+	}
+
+	return newRegexParser(regEx, layoutTime(timeLayout)), nil
+}
+
+// newRegexParser creates a line parser setting the entry fields based on the regular expression group names
+func newRegexParser(regEx *regexp.Regexp, parseTime func(value string) (time.Time, error)) *RegexParser {
+	names := regEx.SubexpNames()
+	setters := make([]fieldSetter, len(names))
+	for i, name := range names {
+		switch {
+		case name == "" || name == dateTimeGroupName:
+			continue
+		case entrySetters[name] != nil:
+			setters[i] = entrySetters[name]
+		default:
+			setters[i] = customFieldSetter(name)
+		}
+	}
+
+	return &RegexParser{
+		regEx:     regEx,
+		timeIndex: regEx.SubexpIndex(dateTimeGroupName),
+		parseTime: parseTime,
+		setters:   setters,
+	}
+}
+
+// RegexParser represents a line parser based on a regular expression.
+// Every regular expression group has its own setter that knows which Entry field the group's value goes to
+type RegexParser struct {
+	regEx     *regexp.Regexp
+	timeIndex int
+	parseTime func(value string) (time.Time, error)
+	setters   []fieldSetter
+}
+
+// ParseTime parses a given log line and attempts to convert its time group into time.Time
+func (p *RegexParser) ParseTime(l string) (time.Time, error) {
+	matches := p.regEx.FindStringSubmatch(l)
+	if len(matches) == 0 {
+		return time.Time{}, fmt.Errorf("line '%s': %w", l, errInvalidLogFormat)
+	}
+
+	dateTime := matches[p.timeIndex]
+	if dateTime == "" {
+		return time.Time{}, fmt.Errorf("invalid date: %w", errInvalidLogFormat)
+	}
+
+	t, err := p.parseTime(dateTime)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return t, nil
+}
+
+// ParseEntry parses a given log line into an Entry.
+// Missing values like "-" for status or bytes are left as zero values
+func (p *RegexParser) ParseEntry(l string) (Entry, error) {
+	matches := p.regEx.FindStringSubmatch(l)
+	if len(matches) == 0 {
+		return Entry{}, fmt.Errorf("line '%s': %w", l, errInvalidLogFormat)
+	}
+
+	var entry Entry
+	t, err := p.parseTime(matches[p.timeIndex])
+	if err != nil {
+		return Entry{}, err
+	}
+	entry.Time = t
+
+	for i, value := range matches {
+		setter := p.setters[i]
+		if setter == nil {
+			continue
+		}
+		err := setter(&entry, value)
+		if err != nil {
+			return Entry{}, err
+		}
+	}
+
+	return entry, nil
+}
+
+// fieldSetter sets a regular expression group value on the right Entry field
+type fieldSetter func(entry *Entry, value string) error
+
+var entrySetters = map[string]fieldSetter{
+	remoteHostGroupName: func(entry *Entry, value string) error {
+		entry.RemoteHost = value
+		return nil
+	},
+	identGroupName: func(entry *Entry, value string) error {
+		entry.Ident = value
+		return nil
+	},
+	userGroupName: func(entry *Entry, value string) error {
+		entry.User = value
+		return nil
+	},
+	methodGroupName: func(entry *Entry, value string) error {
+		entry.Method = value
+		return nil
+	},
+	pathGroupName: func(entry *Entry, value string) error {
+		entry.Path = value
+		return nil
+	},
+	protocolGroupName: func(entry *Entry, value string) error {
+		entry.Protocol = value
+		return nil
+	},
+	statusGroupName: func(entry *Entry, value string) error {
+		if value == "-" || value == "" {
+			return nil
+		}
+		status, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		entry.Status = status
+		return nil
+	},
+	bytesGroupName: func(entry *Entry, value string) error {
+		if value == "-" || value == "" {
+			return nil
+		}
+		bytes, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		entry.Bytes = bytes
+		return nil
+	},
+	refererGroupName: func(entry *Entry, value string) error {
+		entry.Referer = value
+		return nil
+	},
+	userAgentGroupName: func(entry *Entry, value string) error {
+		entry.UserAgent = value
+		return nil
+	},
+}
+
+// customFieldSetter stores the group value inside Entry.Fields under the given name
+func customFieldSetter(name string) fieldSetter {
+	return func(entry *Entry, value string) error {
+		if entry.Fields == nil {
+			entry.Fields = make(map[string]string)
+		}
+		entry.Fields[name] = value
+		return nil
+	}
+}
+
+// durationSetter parses the group value as a number of units and sets it as Entry.Duration
+func durationSetter(unit time.Duration) fieldSetter {
+	return func(entry *Entry, value string) error {
+		if value == "-" || value == "" {
+			return nil
+		}
+		amount, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		entry.Duration = time.Duration(amount * float64(unit))
+		return nil
+	}
+}
+
+// layoutTime parses times using the given time layout
+func layoutTime(layout string) func(value string) (time.Time, error) {
+	return func(value string) (time.Time, error) {
+		return time.Parse(layout, value)
+	}
+}
+
+// unixTime parses times represented as a (fractional) number of units since the unix epoch
+func unixTime(unit time.Duration) func(value string) (time.Time, error) {
+	return func(value string) (time.Time, error) {
+		seconds, fraction := value, ""
+		if i := strings.IndexByte(value, '.'); i >= 0 {
+			seconds, fraction = value[:i], value[i+1:]
+		}
+		whole, err := strconv.ParseInt(seconds, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("parsing time %q: %w", value, errInvalidLogFormat)
+		}
+
+		nanos := whole * int64(unit)
+		if fraction != "" {
+			frac, err := strconv.ParseFloat("0."+fraction, 64)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("parsing time %q: %w", value, errInvalidLogFormat)
+			}
+			// the fraction has the sign of the whole part, i.e. -1.5 is 1.5 units before the epoch
+			if strings.HasPrefix(seconds, "-") {
+				nanos -= int64(frac * float64(unit))
+			} else {
+				nanos += int64(frac * float64(unit))
+			}
+		}
+		return time.Unix(0, nanos).UTC(), nil
+	}
+}
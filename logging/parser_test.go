@@ -0,0 +1,192 @@
+package logging
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type parserSuite struct {
+	suite.Suite
+}
+
+func (s *parserSuite) Test_ParserByName_Success() {
+	for _, name := range ParserNames() {
+		s.Run(name, func() {
+			parser, err := ParserByName(name)
+
+			s.NoError(err)
+			s.NotNil(parser)
+		})
+	}
+}
+
+func (s *parserSuite) Test_ParserByName_Error() {
+	parser, err := ParserByName("unknown")
+
+	s.EqualError(err, "parser 'unknown', available parsers: apache, combined, common, jsonl, nginx, syslog: unknown parser")
+	s.Nil(parser)
+}
+
+func (s *parserSuite) Test_NewRegexParser_Success() {
+	parser, err := NewRegexParser(`^(?P<datetime>\S+) (?P<host>\S+) (?P<status>\d+)$`, time.RFC3339)
+	s.Require().NoError(err)
+	expectedTime, err := time.Parse(time.RFC3339, "2022-03-04T05:30:00Z")
+	s.Require().NoError(err)
+
+	entry, err := parser.ParseEntry("2022-03-04T05:30:00Z web-1 404")
+
+	s.NoError(err)
+	s.Equal(Entry{RemoteHost: "web-1", Time: expectedTime, Status: 404}, entry)
+}
+
+func (s *parserSuite) Test_NewRegexParser_Error() {
+	tests := []struct {
+		name        string
+		pattern     string
+		expectedErr string
+	}{
+		{
+			name:        "Invalid Pattern",
+			pattern:     `^(?P<datetime>\S+`,
+			expectedErr: "error parsing regexp: missing closing ): `^(?P<datetime>\\S+`",
+		},
+		{
+			name:        "Missing DateTime Group",
+			pattern:     `^(?P<host>\S+)$`,
+			expectedErr: `pattern '^(?P<host>\S+)$' has no 'datetime' group: invalid log format`,
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			parser, err := NewRegexParser(test.pattern, time.RFC3339)
+
+			s.EqualError(err, test.expectedErr)
+			s.Nil(parser)
+		})
+	}
+}
+
+func (s *parserSuite) Test_ParseTime_Success() {
+	tests := []struct {
+		name         string
+		parser       string
+		log          string
+		expectedTime string
+		layout       string
+	}{
+		{
+			name:         "Apache",
+			parser:       "apache",
+			log:          `127.0.0.1 user-identifier frank [04/Mar/2022:05:30:00 +0000] "GET /api/endpoint HTTP/1.0" 500 123`,
+			expectedTime: "04/Mar/2022:05:30:00 +0000",
+			layout:       dateTimeFormat,
+		},
+		{
+			name:         "Syslog",
+			parser:       "syslog",
+			log:          `2022-03-04T05:30:00.123456+00:00 web-1 sshd[1234]: Accepted publickey for frank`,
+			expectedTime: "2022-03-04T05:30:00.123456+00:00",
+			layout:       time.RFC3339Nano,
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			parser, err := ParserByName(test.parser)
+			s.Require().NoError(err)
+			expectedTime, err := time.Parse(test.layout, test.expectedTime)
+			s.Require().NoError(err)
+
+			t, err := parser.ParseTime(test.log)
+
+			s.NoError(err)
+			s.True(t.Equal(expectedTime))
+		})
+	}
+}
+
+func (s *parserSuite) Test_ParseTime_Error() {
+	parser := NewApacheParser()
+	tests := []struct {
+		name        string
+		log         string
+		expectedErr string
+	}{
+		{
+			name:        "Empty LogLine",
+			log:         "",
+			expectedErr: "line '': invalid log format",
+		},
+		{
+			name:        "Invalid LogLine",
+			log:         "this log line is not valid",
+			expectedErr: "line 'this log line is not valid': invalid log format",
+		},
+		{
+			name:        "Invalid DateFormat",
+			log:         `127.0.0.1 user-identifier frank [36/Mar/2022:05:30:00 +0000] "GET /api/endpoint HTTP/1.0" 500 123`,
+			expectedErr: `parsing time "36/Mar/2022:05:30:00 +0000": day out of range`,
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			t, err := parser.ParseTime(test.log)
+
+			s.EqualError(err, test.expectedErr)
+			s.True(t.IsZero())
+		})
+	}
+}
+
+func (s *parserSuite) Test_unixTime() {
+	tests := []struct {
+		name         string
+		value        string
+		unit         time.Duration
+		expectedTime time.Time
+	}{
+		{
+			name:         "Seconds",
+			value:        "1646371800",
+			unit:         time.Second,
+			expectedTime: time.Unix(1646371800, 0),
+		},
+		{
+			name:         "Fractional Seconds",
+			value:        "1646371800.25",
+			unit:         time.Second,
+			expectedTime: time.Unix(1646371800, int64(250*time.Millisecond)),
+		},
+		{
+			name:         "Fractional Milliseconds",
+			value:        "1646371800000.5",
+			unit:         time.Millisecond,
+			expectedTime: time.Unix(1646371800, int64(500*time.Microsecond)),
+		},
+		{
+			name:         "Negative Fractional Seconds",
+			value:        "-1.5",
+			unit:         time.Second,
+			expectedTime: time.Unix(0, int64(-1500*time.Millisecond)),
+		},
+		{
+			name:         "Negative Fraction Only",
+			value:        "-0.5",
+			unit:         time.Second,
+			expectedTime: time.Unix(0, int64(-500*time.Millisecond)),
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			t, err := unixTime(test.unit)(test.value)
+
+			s.NoError(err)
+			s.True(test.expectedTime.Equal(t), t.String())
+		})
+	}
+}
+
+func TestParser(t *testing.T) {
+	suite.Run(t, new(parserSuite))
+}
@@ -3,37 +3,153 @@ package logging
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"log"
 	"os"
 	"path"
-	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
+var (
+	errInvalidTimeWindow = errors.New("invalid time window")
+	errUnsupportedMode   = errors.New("unsupported mode")
+	// errSkipped and errLimitReached never leave the reader: emitting a line that was skipped or filtered out
+	// returns errSkipped, emitting the last line of the limit returns errLimitReached, which stops the reading
+	errSkipped      = errors.New("skipped")
+	errLimitReached = errors.New("limit reached")
+)
+
 type fileInfo struct {
 	name    string
 	modTime time.Time
 	size    int64
+	// first and last are the times of the first and the last logs inside the file,
+	// when ordering by modification time, last is the modification time and first is not set
+	first time.Time
+	last  time.Time
+}
+
+// ParseSince parses the duration of a time window ending now (ReaderConfig.Since),
+// i.e. 90s or 2m30s, plain numbers are minutes to stay compatible with LastNMinutes
+func ParseSince(value string) (time.Duration, error) {
+	minutes, err := strconv.Atoi(value)
+	if err == nil {
+		return time.Duration(minutes) * time.Minute, nil
+	}
+	return time.ParseDuration(value)
 }
 
 // ReaderConfig represents the configuration to start the log reader
 type ReaderConfig struct {
-	Directory    string
+	Directory string
+	// LastNMinutes is the number of minutes of the time window ending now.
+	//
+	// Deprecated: use Since, LastNMinutes is only used when Since is not set
 	LastNMinutes int
+	// Since is the duration of the time window ending now, i.e. read the logs of the last 90s
+	Since time.Duration
+	// From is the beginning of the time window, when set it takes precedence over Since
+	From time.Time
+	// To is the end of the time window, when not set logs are read till the very end
+	To time.Time
+	// Parser understands the format of the log lines, apache common/combined log format is used by default
+	Parser LineParser
+	// Follow keeps streaming the logs appended after the time window was read, just like tail -F,
+	// surviving log rotation and picking up new log files, till the context is canceled
+	Follow bool
+	// FollowInterval is how often the followed log file is checked for new logs, 250ms by default
+	FollowInterval time.Duration
+	// Merge reads log files overlapping in time, i.e. the logs of multiple hosts behind a load balancer,
+	// merging their logs so they're globally ordered by time
+	Merge bool
+	// Filter keeps only the logs matching the filter expression, see ParseFilter
+	Filter *Filter
+	// Order is the order the log files are read in: mtime (default) or content
+	Order string
+	// MaxLineSize is the maximum size of a log line, 1MB by default
+	MaxLineSize int
+	// LongLines is what happens with the lines longer than MaxLineSize: fail (default) or skip,
+	// in which case the binary search probes the lines next to them instead
+	LongLines string
+	// Malformed is what happens with the lines that can't be parsed: fail (default), skip or quarantine.
+	// When not set, the raw output (Read without a filter) prints the logs of the time window without parsing them
+	Malformed string
+	// Quarantine receives the malformed lines, one per line, when they are quarantined
+	Quarantine io.Writer
+	// Reverse reads the logs newest first, walking the log files backwards from the end of the time window
+	// till its beginning, which is handy along with Limit to get the latest logs without reading the whole window
+	Reverse bool
+	// Workers is the number of goroutines parsing and filtering the logs of plain files in parallel,
+	// the files are split into chunks and the logs are still emitted in order. 0 or 1 reads the logs sequentially
+	Workers int
+	// Limit stops reading after that many logs were read (the ones matching the filter), 0 means no limit
+	Limit int
+	// Checkpoint resumes reading right after the log of the checkpoint, returned by Reader.Checkpoint after a previous read,
+	// instead of the beginning of the time window (Since, From). The logs newer than the time of the checkpoint are read
+	// when its log file can't be found anymore, i.e. it was compressed or deleted by a log rotation
+	Checkpoint *Checkpoint
+	// Logger receives the warnings, i.e. files whose modification time disagrees with their content.
+	// No warnings are logged when not set
+	Logger *log.Logger
 }
 
 // NewReader creates a new instance of log reader
 func NewReader(cfg ReaderConfig) (*Reader, error) {
+	if cfg.Since == 0 {
+		cfg.Since = time.Duration(cfg.LastNMinutes) * time.Minute
+	}
+	if !cfg.From.IsZero() && !cfg.To.IsZero() && cfg.To.Before(cfg.From) {
+		return nil, fmt.Errorf("from %v is after to %v: %w", cfg.From, cfg.To, errInvalidTimeWindow)
+	}
+	if cfg.Follow && !cfg.To.IsZero() {
+		return nil, fmt.Errorf("follow with to %v: %w", cfg.To, errInvalidTimeWindow)
+	}
+	if cfg.Follow && cfg.Merge {
+		return nil, fmt.Errorf("follow with merge: %w", errUnsupportedMode)
+	}
+	if cfg.Checkpoint != nil && cfg.Merge {
+		return nil, fmt.Errorf("checkpoint with merge: %w", errUnsupportedMode)
+	}
+	if cfg.Reverse && (cfg.Follow || cfg.Merge || cfg.Checkpoint != nil) {
+		return nil, fmt.Errorf("reverse with follow, merge or checkpoint: %w", errUnsupportedMode)
+	}
+	if cfg.LongLines != "" && cfg.LongLines != LongLinesFail && cfg.LongLines != LongLinesSkip {
+		return nil, fmt.Errorf("long lines '%s': %w", cfg.LongLines, errUnknownPolicy)
+	}
+	switch cfg.Malformed {
+	case "", MalformedFail, MalformedSkip:
+	case MalformedQuarantine:
+		if cfg.Quarantine == nil {
+			return nil, fmt.Errorf("quarantine without a writer: %w", errUnsupportedMode)
+		}
+	default:
+		return nil, fmt.Errorf("malformed '%s': %w", cfg.Malformed, errUnknownPolicy)
+	}
+
 	files, err := ioutil.ReadDir(cfg.Directory)
 	if err != nil {
 		return nil, err
 	}
 
 	filesInfo := make([]fileInfo, 0, len(files))
+	var unsupported []string
 	for _, file := range files {
-		if file.IsDir() {
+		// skip directories and sidecar indexes
+		if file.IsDir() || strings.HasSuffix(file.Name(), indexExtension) {
+			continue
+		}
+		// the log files compressed using an unsupported format (zstd) can't be read, they are skipped with a warning
+		format, err := fileCompression(path.Join(cfg.Directory, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if format == zstdCompression {
+			unsupported = append(unsupported, file.Name())
 			continue
 		}
 
@@ -44,48 +160,228 @@ func NewReader(cfg ReaderConfig) (*Reader, error) {
 		}
 		filesInfo = append(filesInfo, fi)
 	}
-	sort.Slice(filesInfo, func(i, j int) bool {
-		return filesInfo[i].modTime.Sub(filesInfo[j].modTime) < 0
-	})
+
+	parser := cfg.Parser
+	if parser == nil {
+		parser = NewApacheParser()
+	}
 
 	lr := &Reader{
 		cfg:       cfg,
+		parser:    parser,
 		filesInfo: filesInfo,
 		nowFunc: func() time.Time {
 			return time.Now().UTC()
 		},
+		chunkSize: defaultChunkSize,
+	}
+	for _, name := range unsupported {
+		lr.warnf("file '%s': zstd: %v, skipped", name, errUnsupportedCompression)
+	}
+	err = lr.orderFiles()
+	if err != nil {
+		return nil, err
 	}
 	return lr, nil
 }
 
 // Reader represents the application log reader type
 // responsible for reading logs from a given directory
-// that were written in the last N minutes
+// that were written in the last T time
 type Reader struct {
 	cfg       ReaderConfig
+	parser    LineParser
 	filesInfo []fileInfo
 	nowFunc   func() time.Time
+	// tail is the position the reading stopped at, used to follow the logs from there
+	tail position
+	// skipped counts the lines skipped by the last read
+	skipped Skipped
+	// last is the last line read, used to checkpoint the reading
+	last Line
+	// parseLines tells the workers whether to parse the lines, aggregate has the workers aggregate them as well
+	parseLines bool
+	aggregate  func(worker int, entry Entry) error
+	// chunkSize is the size of the chunks the logs are split into for the workers
+	chunkSize int64
 }
 
 // Read reads the log files using the given LogReader configuration
-// and stores it inside a local bytes buffer to be displayed later
+// and stores it inside a local bytes buffer to be displayed later.
+// Reading stops as soon as the context is done, which is not considered an error
 func (r *Reader) Read(ctx context.Context, w io.Writer) error {
+	// the lines are printed as they are, they're only parsed to be filtered or to apply the malformed lines policy
+	parse := r.cfg.Filter != nil || r.cfg.Malformed != ""
+	writer := bufio.NewWriter(w)
+	emit := func(line Line) error {
+		if parse {
+			_, err := r.entry(line)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err := writer.WriteString(line.Text + "\n")
+		if err != nil {
+			return err
+		}
+		return writer.Flush()
+	}
+
+	return r.run(ctx, parse, emit)
+}
+
+// ReadEntries reads the log files using the given LogReader configuration
+// and calls fn with every parsed log line (Entry) that is within the time window and matches the filter.
+// Reading stops at the first error returned by fn or as soon as the context is done
+func (r *Reader) ReadEntries(ctx context.Context, fn func(Entry) error) error {
+	emit := func(line Line) error {
+		entry, err := r.entry(line)
+		if err != nil {
+			return err
+		}
+		return fn(entry)
+	}
+
+	return r.run(ctx, true, emit)
+}
+
+// entry parses a log line, unless a worker already did, applying the malformed lines policy and the filter.
+// err == errSkipped -> the line was either skipped or filtered out
+func (r *Reader) entry(line Line) (Entry, error) {
+	parsed := line.parsed
+	if parsed == nil {
+		parsed = r.parse(line.Text)
+	}
+	if parsed.err != nil && parsed.err != errSkipped {
+		err := r.malformed(line.Text, parsed.err)
+		if err != nil {
+			return Entry{}, err
+		}
+		return Entry{}, errSkipped
+	}
+	return parsed.entry, parsed.err
+}
+
+// parse parses a log line and applies the filter, it's safe to be called by multiple workers at once
+func (r *Reader) parse(line string) *parsedLine {
+	entry, err := r.parser.ParseEntry(line)
+	if err != nil {
+		return &parsedLine{err: err}
+	}
+	if r.cfg.Filter != nil && !r.cfg.Filter.Match(entry) {
+		return &parsedLine{err: errSkipped}
+	}
+	return &parsedLine{entry: entry}
+}
+
+// run reads the logs of the time window and then follows the new logs, if configured to.
+// parse tells whether the lines are parsed, so the workers parse them in parallel, see ReaderConfig.Workers
+func (r *Reader) run(ctx context.Context, parse bool, emit func(line Line) error) error {
 	select {
 	case <-ctx.Done():
 		return nil
 	default:
-		return r.read(w)
 	}
+	r.skipped = Skipped{}
+	r.last = Line{}
+	r.parseLines = parse
+	defer r.summarize()
+
+	count := 0
+	err := r.runMode(ctx, func(line Line) error {
+		err := emit(line)
+		if err == errSkipped {
+			r.last = line
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		r.last = line
+		count++
+		if r.cfg.Limit > 0 && count >= r.cfg.Limit {
+			return errLimitReached
+		}
+		return nil
+	})
+	// a canceled read is not a failure, it just stops, the same goes for a read that reached its limit
+	if ctxErr := ctx.Err(); ctxErr != nil && errors.Is(err, ctxErr) {
+		return nil
+	}
+	if err == errLimitReached {
+		return nil
+	}
+	return err
+}
+
+func (r *Reader) runMode(ctx context.Context, emit func(line Line) error) error {
+	if r.cfg.Merge {
+		return r.merge(ctx, emit)
+	}
+	if r.cfg.Reverse {
+		return r.reverse(ctx, emit)
+	}
+
+	err := r.read(ctx, emit)
+	if err != nil || !r.cfg.Follow {
+		return err
+	}
+	return r.follow(ctx, emit)
+}
+
+func (r *Reader) warnf(format string, v ...interface{}) {
+	if r.cfg.Logger != nil {
+		r.cfg.Logger.Printf("warning: "+format, v...)
+	}
+}
+
+// window returns the time window the logs are read for
+// a zero to means there is no upper limit for the window
+func (r *Reader) window() (from, to time.Time) {
+	from = r.cfg.From
+	if from.IsZero() {
+		from = r.nowFunc().Add(-r.cfg.Since)
+	}
+	return from, r.cfg.To
+}
+
+// windowEnd returns the offset right after the last log of the file that is within the time window
+func (r *Reader) windowEnd(ctx context.Context, file *File, to time.Time) (int64, error) {
+	if to.IsZero() {
+		stat, err := file.Stat()
+		if err != nil {
+			return -1, err
+		}
+		return stat.Size(), nil
+	}
+
+	return file.indexTimeEnd(ctx, to)
 }
 
 // if there are an infinite number of log files,
 // knowing the exact log rotation period may help
 // skip iterations up to the very close of the log file
-func (r *Reader) read(w io.Writer) error {
+func (r *Reader) read(ctx context.Context, emit func(line Line) error) error {
+	from, to := r.window()
+	if r.cfg.Checkpoint != nil {
+		resumed, err := r.resume(ctx, emit, to)
+		if err != nil || resumed {
+			return err
+		}
+		if !r.cfg.Checkpoint.Time.IsZero() {
+			return r.readSince(ctx, emit, to)
+		}
+	}
+	return r.readWindow(ctx, emit, from, to)
+}
+
+// readWindow reads all the logs of the time window, looking for the file and the offset the window begins at
+func (r *Reader) readWindow(ctx context.Context, emit func(line Line) error, from, to time.Time) error {
 	logFileIndex := -1
 	for i, fi := range r.filesInfo {
-		nowMinusT := r.nowFunc().Add(-time.Duration(r.cfg.LastNMinutes) * time.Minute)
-		if nowMinusT.Sub(fi.modTime) <= 0 {
+		if !fi.endsBefore(from) {
 			logFileIndex = i
 			break
 		}
@@ -94,101 +390,259 @@ func (r *Reader) read(w io.Writer) error {
 		return nil
 	}
 
-	filePath := path.Join(r.cfg.Directory, r.filesInfo[logFileIndex].name)
+	// the modification time only approximates the time of the last log inside a file,
+	// so the window may as well begin inside any of the following files
+	for ; logFileIndex < len(r.filesInfo); logFileIndex++ {
+		offset, windowEnded, err := r.readFirst(ctx, emit, r.filesInfo[logFileIndex], from, to)
+		if err != nil {
+			return err
+		}
+		if windowEnded {
+			return nil
+		}
+		if offset >= 0 {
+			break
+		}
+	}
+	if logFileIndex+1 >= len(r.filesInfo) {
+		return nil
+	}
+	return r.readOthers(ctx, emit, r.filesInfo[logFileIndex+1:], from, to)
+}
+
+// readOthers reads all the logs of the files following the one the time window begins in, till the end of the window
+func (r *Reader) readOthers(ctx context.Context, emit func(line Line) error, others []fileInfo, from, to time.Time) error {
+	for _, fi := range others {
+		windowEnded, err := r.readOther(ctx, emit, fi, from, to)
+		if err != nil {
+			return err
+		}
+
+		// the window ends inside the current file
+		// so all the logs inside the remaining files are newer than the window
+		if windowEnded {
+			return nil
+		}
+	}
+	return nil
+}
+
+// readFirst looks for the beginning of the time window inside the given file
+// and reads all the logs from there till the end of the window or the end of the file.
+// offset == -1 -> all the logs inside the log file are older than the window
+// windowEnded == true -> the window ends inside the log file, no need to read any other file
+func (r *Reader) readFirst(ctx context.Context, emit func(line Line) error, fi fileInfo, from, to time.Time) (offset int64, windowEnded bool, err error) {
+	filePath := path.Join(r.cfg.Directory, fi.name)
 	f, err := os.Open(filePath)
 	defer func() { _ = f.Close() }()
 	if err != nil {
-		return err
+		return -1, false, err
 	}
 
-	nowMinusT := r.nowFunc().Add(-time.Duration(r.cfg.LastNMinutes) * time.Minute)
-	file := NewFile(f)
-	offset, err := file.IndexTime(nowMinusT)
+	// compressed files can't be searched, so they have to be scanned from the very beginning,
+	// unless they are seekable archives, in which case the scan begins at the right frame
+	decompressed, compressed, err := decompress(f)
 	if err != nil {
-		return err
+		return -1, false, err
 	}
+	if compressed {
+		archive, ok, err := seekArchive(f, from)
+		if err != nil {
+			return -1, false, err
+		}
+		if ok {
+			decompressed = archive
+		}
 
-	others := r.filesInfo[logFileIndex+1 : len(r.filesInfo)]
-	readTheRest := func() error {
-		for _, fi := range others {
-			chunks := r.stream(fi)
-			for c := range chunks {
-				if c.err != nil {
-					return c.err
-				}
-
-				_, err := fmt.Fprintln(w, c.line)
-				if err != nil {
-					return err
-				}
-			}
+		found, windowEnded, err := r.scan(ctx, emit, decompressed, fi.name, from, to)
+		if err != nil || !found {
+			return -1, windowEnded, err
 		}
-		return nil
+		return 0, windowEnded, nil
 	}
 
-	if offset < 0 {
-		if logFileIndex+1 >= len(r.filesInfo) {
-			return nil
+	file := r.newFile(f)
+	end, err := r.windowEnd(ctx, file, to)
+	if err != nil {
+		return -1, false, err
+	}
+	r.tail = position{name: fi.name, offset: end}
+
+	offset, err = file.indexTime(ctx, from)
+	if err != nil || offset < 0 {
+		return -1, false, err
+	}
+	if offset >= end {
+		return offset, true, nil
+	}
+
+	_, err = f.Seek(offset, io.SeekStart)
+	if err != nil {
+		return -1, false, err
+	}
+	if r.workers() > 1 {
+		err = r.parallel(ctx, emit, file, fi.name, offset, end)
+		if err != nil {
+			return -1, false, err
 		}
+		return offset, end < fi.size, nil
+	}
 
-		nowMinusT := r.nowFunc().Add(-time.Duration(r.cfg.LastNMinutes) * time.Minute)
-		fi := r.filesInfo[logFileIndex+1]
-		if nowMinusT.Sub(fi.modTime) > 0 {
-			return nil
+	lines := r.lines(ctx, io.LimitReader(f, end-offset), fi.name, offset)
+	for {
+		line, err := lines.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return -1, false, err
+		}
+		err = emit(Line{Text: line, File: fi.name, Offset: lines.start})
+		if err != nil {
+			return -1, false, err
 		}
-		return readTheRest()
 	}
 
-	_, err = f.Seek(offset, io.SeekStart)
+	return offset, end < fi.size, nil
+}
+
+// readOther reads all the logs of a file following the one the time window begins in, till the end of the window.
+// windowEnded == true -> the window ends inside the log file, no need to read any other file
+func (r *Reader) readOther(ctx context.Context, emit func(line Line) error, fi fileInfo, from, to time.Time) (windowEnded bool, err error) {
+	f, err := os.Open(path.Join(r.cfg.Directory, fi.name))
+	defer func() { _ = f.Close() }()
 	if err != nil {
-		return err
+		return false, err
 	}
-	writer := bufio.NewWriter(w)
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		_, err := writer.WriteString(scanner.Text() + "\n")
+
+	decompressed, compressed, err := decompress(f)
+	if err != nil {
+		return false, err
+	}
+	if compressed {
+		_, windowEnded, err := r.scan(ctx, emit, decompressed, fi.name, from, to)
+		return windowEnded, err
+	}
+
+	file := r.newFile(f)
+	end, err := r.windowEnd(ctx, file, to)
+	if err != nil {
+		return false, err
+	}
+	r.tail = position{name: fi.name, offset: end}
+	if r.workers() > 1 {
+		err = r.parallel(ctx, emit, file, fi.name, 0, end)
 		if err != nil {
-			return err
+			return false, err
+		}
+		return end < fi.size, nil
+	}
+
+	// the streaming goroutine stops as soon as this function returns, even when emitting failed
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	for c := range r.stream(streamCtx, fi, end) {
+		if c.err != nil {
+			return false, c.err
 		}
-		err = writer.Flush()
+		// a line may have been streamed right before the context was done
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+
+		err := emit(c.line)
 		if err != nil {
-			return err
+			return false, err
 		}
 	}
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return end < fi.size, nil
+}
 
-	return readTheRest()
+// scan linearly reads the logs that are within the time window, parsing the time of each log line.
+// It's used for files that can't be searched, like compressed ones.
+// found == true -> at least one log inside the window was found
+// windowEnded == true -> a log newer than the window was found, no need to read any further
+func (r *Reader) scan(ctx context.Context, emit func(line Line) error, reader io.Reader, name string, from, to time.Time) (found, windowEnded bool, err error) {
+	lines := r.lines(ctx, reader, name, 0)
+	for {
+		line, err := lines.next()
+		if err == io.EOF {
+			return found, false, nil
+		}
+		if err != nil {
+			return found, false, err
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		logTime, err := r.parser.ParseTime(line)
+		if err != nil {
+			err = r.malformed(line, err)
+			if err != nil {
+				return found, false, err
+			}
+			continue
+		}
+		if logTime.Before(from) {
+			continue
+		}
+		if !to.IsZero() && logTime.After(to) {
+			return found, true, nil
+		}
+
+		found = true
+		err = emit(Line{Text: line, File: name, Offset: -1})
+		if err != nil {
+			return found, false, err
+		}
+	}
 }
 
 type chunk struct {
-	line string
+	line Line
 	err  error
 }
 
-func (r *Reader) stream(fi fileInfo) chan chunk {
+// stream reads the logs of the file till the end offset inside a separate goroutine.
+// The goroutine stops, closing both the file and the channel, once the logs were read or as soon as the context is done
+func (r *Reader) stream(ctx context.Context, fi fileInfo, end int64) <-chan chunk {
 	out := make(chan chunk)
 	go func() {
-		filePath := path.Join(r.cfg.Directory, fi.name)
-		file, err := os.Open(filePath)
-		defer func() {
-			_ = file.Close()
-		}()
-		if err != nil {
-			out <- chunk{
-				err:  err,
-				line: "",
+		defer close(out)
+		send := func(c chunk) bool {
+			select {
+			case out <- c:
+				return true
+			case <-ctx.Done():
+				return false
 			}
+		}
+
+		file, err := os.Open(path.Join(r.cfg.Directory, fi.name))
+		if err != nil {
+			send(chunk{err: err})
 			return
 		}
+		defer func() { _ = file.Close() }()
 
-		scanner := bufio.NewScanner(file)
-		for scanner.Scan() {
-			out <- chunk{
-				err:  nil,
-				line: scanner.Text(),
+		lines := r.lines(ctx, io.LimitReader(file, end), fi.name, 0)
+		for {
+			line, err := lines.next()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				send(chunk{err: err})
+				return
+			}
+			if !send(chunk{line: Line{Text: line, File: fi.name, Offset: lines.start}}) {
+				return
 			}
 		}
-
-		close(out)
 	}()
 	return out
 }
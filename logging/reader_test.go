@@ -2,10 +2,15 @@ package logging
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"os"
 	"path"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 
@@ -61,8 +66,8 @@ func (s *readerSuite) Test_NewReader_Success() {
 		s.createLogFile(dir, fmt.Sprintf("http-%d.log", i+1), fmt.Sprintf("log %d", i+1))
 	}
 	cfg := ReaderConfig{
-		Directory:    dir,
-		LastNMinutes: 3,
+		Directory: dir,
+		Since:     3 * time.Minute,
 	}
 
 	reader, err := NewReader(cfg)
@@ -84,15 +89,46 @@ func (s *readerSuite) Test_NewReader_Error() {
 	s.Nil(reader)
 }
 
+func (s *readerSuite) Test_NewReader_InvalidTimeWindow() {
+	cfg := ReaderConfig{
+		Directory: testDataDir,
+		From:      s.nowFunc(),
+		To:        s.nowFunc().Add(-time.Minute),
+	}
+
+	reader, err := NewReader(cfg)
+
+	s.EqualError(err, "from 2022-03-03 02:45:00 +0000 UTC is after to 2022-03-03 02:44:00 +0000 UTC: invalid time window")
+	s.Nil(reader)
+}
+
 func (s *readerSuite) Test_Read_Success() {
 	tests := []struct {
 		name         string
+		since        time.Duration
 		lastNMinutes int
 		expectedLogs string
 	}{
 		{
-			name:         "Last Minute",
-			lastNMinutes: 1,
+			name:  "Last 20 Seconds",
+			since: 20 * time.Second,
+			expectedLogs: `127.0.0.1 user-identifier frank [03/Mar/2022:02:45:00 +0000] "GET /api/endpoint HTTP/1.0" 500 123
+127.0.0.1 user-identifier frank [03/Mar/2022:02:45:20 +0000] "GET /api/endpoint HTTP/1.0" 500 123
+127.0.0.1 user-identifier frank [03/Mar/2022:02:45:40 +0000] "GET /api/endpoint HTTP/1.0" 500 123
+`,
+		},
+		{
+			name:  "Last Minute",
+			since: time.Minute,
+			expectedLogs: `127.0.0.1 user-identifier frank [03/Mar/2022:02:44:00 +0000] "GET /api/endpoint HTTP/1.0" 500 123
+127.0.0.1 user-identifier frank [03/Mar/2022:02:45:00 +0000] "GET /api/endpoint HTTP/1.0" 500 123
+127.0.0.1 user-identifier frank [03/Mar/2022:02:45:20 +0000] "GET /api/endpoint HTTP/1.0" 500 123
+127.0.0.1 user-identifier frank [03/Mar/2022:02:45:40 +0000] "GET /api/endpoint HTTP/1.0" 500 123
+`,
+		},
+		{
+			name:  "Last Two Minutes",
+			since: 2 * time.Minute,
 			expectedLogs: `127.0.0.1 user-identifier frank [03/Mar/2022:02:43:20 +0000] "GET /api/endpoint HTTP/1.0" 500 123
 127.0.0.1 user-identifier frank [03/Mar/2022:02:43:40 +0000] "GET /api/endpoint HTTP/1.0" 500 123
 127.0.0.1 user-identifier frank [03/Mar/2022:02:44:00 +0000] "GET /api/endpoint HTTP/1.0" 500 123
@@ -102,10 +138,9 @@ func (s *readerSuite) Test_Read_Success() {
 `,
 		},
 		{
-			name:         "Last Two Minutes",
-			lastNMinutes: 2,
-			expectedLogs: `127.0.0.1 user-identifier frank [03/Mar/2022:02:43:20 +0000] "GET /api/endpoint HTTP/1.0" 500 123
-127.0.0.1 user-identifier frank [03/Mar/2022:02:43:40 +0000] "GET /api/endpoint HTTP/1.0" 500 123
+			name:  "Last 90 Seconds",
+			since: 90 * time.Second,
+			expectedLogs: `127.0.0.1 user-identifier frank [03/Mar/2022:02:43:40 +0000] "GET /api/endpoint HTTP/1.0" 500 123
 127.0.0.1 user-identifier frank [03/Mar/2022:02:44:00 +0000] "GET /api/endpoint HTTP/1.0" 500 123
 127.0.0.1 user-identifier frank [03/Mar/2022:02:45:00 +0000] "GET /api/endpoint HTTP/1.0" 500 123
 127.0.0.1 user-identifier frank [03/Mar/2022:02:45:20 +0000] "GET /api/endpoint HTTP/1.0" 500 123
@@ -160,6 +195,7 @@ func (s *readerSuite) Test_Read_Success() {
 			buf := &bytes.Buffer{}
 			cfg := ReaderConfig{
 				Directory:    testDataDir,
+				Since:        test.since,
 				LastNMinutes: test.lastNMinutes,
 			}
 			reader, err := NewReader(cfg)
@@ -174,6 +210,653 @@ func (s *readerSuite) Test_Read_Success() {
 	}
 }
 
+func (s *readerSuite) Test_Read_TimeWindow() {
+	tests := []struct {
+		name         string
+		from         string
+		to           string
+		expectedLogs string
+	}{
+		{
+			name: "Inside One File",
+			from: "03/Mar/2022:02:42:00 +0000",
+			to:   "03/Mar/2022:02:42:10 +0000",
+			expectedLogs: `127.0.0.1 user-identifier frank [03/Mar/2022:02:42:00 +0000] "GET /api/endpoint HTTP/1.0" 500 123
+`,
+		},
+		{
+			name: "Ends At A Log",
+			from: "03/Mar/2022:02:41:50 +0000",
+			to:   "03/Mar/2022:02:42:00 +0000",
+			expectedLogs: `127.0.0.1 user-identifier frank [03/Mar/2022:02:42:00 +0000] "GET /api/endpoint HTTP/1.0" 500 123
+`,
+		},
+		{
+			name: "Across Multiple Files",
+			from: "03/Mar/2022:02:42:10 +0000",
+			to:   "03/Mar/2022:02:45:10 +0000",
+			expectedLogs: `127.0.0.1 user-identifier frank [03/Mar/2022:02:42:20 +0000] "GET /api/endpoint HTTP/1.0" 500 123
+127.0.0.1 user-identifier frank [03/Mar/2022:02:43:20 +0000] "GET /api/endpoint HTTP/1.0" 500 123
+127.0.0.1 user-identifier frank [03/Mar/2022:02:43:40 +0000] "GET /api/endpoint HTTP/1.0" 500 123
+127.0.0.1 user-identifier frank [03/Mar/2022:02:44:00 +0000] "GET /api/endpoint HTTP/1.0" 500 123
+127.0.0.1 user-identifier frank [03/Mar/2022:02:45:00 +0000] "GET /api/endpoint HTTP/1.0" 500 123
+`,
+		},
+		{
+			name: "Ends At File Boundary",
+			from: "03/Mar/2022:02:41:00 +0000",
+			to:   "03/Mar/2022:02:42:20 +0000",
+			expectedLogs: `127.0.0.1 user-identifier frank [03/Mar/2022:02:41:40 +0000] "GET /api/endpoint HTTP/1.0" 500 123
+127.0.0.1 user-identifier frank [03/Mar/2022:02:42:00 +0000] "GET /api/endpoint HTTP/1.0" 500 123
+127.0.0.1 user-identifier frank [03/Mar/2022:02:42:20 +0000] "GET /api/endpoint HTTP/1.0" 500 123
+`,
+		},
+		{
+			name:         "Between Files",
+			from:         "03/Mar/2022:02:42:30 +0000",
+			to:           "03/Mar/2022:02:43:00 +0000",
+			expectedLogs: ``,
+		},
+		{
+			name:         "After All Logs",
+			from:         "03/Mar/2022:03:00:00 +0000",
+			to:           "03/Mar/2022:04:00:00 +0000",
+			expectedLogs: ``,
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			from, err := time.Parse(dateTimeFormat, test.from)
+			s.Require().NoError(err)
+			to, err := time.Parse(dateTimeFormat, test.to)
+			s.Require().NoError(err)
+			ctx := context.Background()
+			buf := &bytes.Buffer{}
+			cfg := ReaderConfig{
+				Directory: testDataDir,
+				From:      from,
+				To:        to,
+			}
+			reader, err := NewReader(cfg)
+			s.Require().NoError(err)
+			reader.nowFunc = s.nowFunc
+
+			err = reader.Read(ctx, buf)
+
+			s.NoError(err)
+			s.Equal(test.expectedLogs, buf.String())
+		})
+	}
+}
+
+func (s *readerSuite) Test_Read_Compressed() {
+	dir := "test/compressed"
+	s.Require().NoError(os.MkdirAll(dir, 0777))
+	defer func() {
+		s.Require().NoError(os.RemoveAll(dir))
+	}()
+	// logrotate style: the older files are compressed, the newest one is not
+	files := []struct {
+		name       string
+		logs       []string
+		compressed bool
+	}{
+		{name: "http.log.2.gz", logs: []string{"02:41:40", "02:42:00", "02:42:20"}, compressed: true},
+		{name: "http.log.1.gz", logs: []string{"02:43:20", "02:43:40", "02:44:00"}, compressed: true},
+		{name: "http.log", logs: []string{"02:45:00", "02:45:20", "02:45:40"}},
+	}
+	for _, file := range files {
+		var logs string
+		for _, t := range file.logs {
+			logs += fmt.Sprintf("127.0.0.1 user-identifier frank [03/Mar/2022:%s +0000] \"GET /api/endpoint HTTP/1.0\" 500 123\n", t)
+		}
+		f := s.createLogFile(dir, file.name, "")
+		if file.compressed {
+			gz := gzip.NewWriter(f)
+			_, err := gz.Write([]byte(logs))
+			s.Require().NoError(err)
+			s.Require().NoError(gz.Close())
+		} else {
+			_, err := f.WriteString(logs)
+			s.Require().NoError(err)
+		}
+		s.Require().NoError(f.Close())
+		modTime, err := time.Parse(dateTimeFormat, "03/Mar/2022:"+file.logs[len(file.logs)-1]+" +0000")
+		s.Require().NoError(err)
+		s.Require().NoError(os.Chtimes(path.Join(dir, file.name), modTime, modTime))
+	}
+	tests := []struct {
+		name         string
+		from         string
+		to           string
+		expectedLogs []string
+	}{
+		{
+			name:         "Inside Compressed File",
+			from:         "02:42:00",
+			to:           "02:42:00",
+			expectedLogs: []string{"02:42:00"},
+		},
+		{
+			name:         "Across Compressed Files",
+			from:         "02:42:10",
+			to:           "02:43:40",
+			expectedLogs: []string{"02:42:20", "02:43:20", "02:43:40"},
+		},
+		{
+			name:         "From Compressed To Plain File",
+			from:         "02:44:00",
+			to:           "02:45:20",
+			expectedLogs: []string{"02:44:00", "02:45:00", "02:45:20"},
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			from, err := time.Parse(dateTimeFormat, "03/Mar/2022:"+test.from+" +0000")
+			s.Require().NoError(err)
+			to, err := time.Parse(dateTimeFormat, "03/Mar/2022:"+test.to+" +0000")
+			s.Require().NoError(err)
+			ctx := context.Background()
+			buf := &bytes.Buffer{}
+			reader, err := NewReader(ReaderConfig{
+				Directory: dir,
+				From:      from,
+				To:        to,
+			})
+			s.Require().NoError(err)
+			reader.nowFunc = s.nowFunc
+
+			err = reader.Read(ctx, buf)
+
+			var expectedLogs string
+			for _, t := range test.expectedLogs {
+				expectedLogs += fmt.Sprintf("127.0.0.1 user-identifier frank [03/Mar/2022:%s +0000] \"GET /api/endpoint HTTP/1.0\" 500 123\n", t)
+			}
+			s.NoError(err)
+			s.Equal(expectedLogs, buf.String())
+		})
+	}
+}
+
+func (s *readerSuite) Test_Read_OrderByContent() {
+	dir := "test/order"
+	s.Require().NoError(os.MkdirAll(dir, 0777))
+	defer func() {
+		s.Require().NoError(os.RemoveAll(dir))
+	}()
+	// the files were copied without preserving their modification times, so the newest file looks like the oldest
+	files := []struct {
+		name    string
+		logs    []string
+		modTime string
+	}{
+		{name: "http-1.log", logs: []string{"02:41:40", "02:42:00", "02:42:20"}, modTime: "02:50:00"},
+		{name: "http-2.log", logs: []string{"02:43:20", "02:43:40", "02:44:00"}, modTime: "02:50:20"},
+		{name: "http-3.log", logs: []string{"02:45:00", "02:45:20", "02:45:40"}, modTime: "02:45:30"},
+	}
+	for _, file := range files {
+		var logs string
+		for _, t := range file.logs {
+			logs += fmt.Sprintf("127.0.0.1 user-identifier frank [03/Mar/2022:%s +0000] \"GET /api/endpoint HTTP/1.0\" 500 123\n", t)
+		}
+		s.Require().NoError(s.createLogFile(dir, file.name, logs).Close())
+		modTime, err := time.Parse(dateTimeFormat, "03/Mar/2022:"+file.modTime+" +0000")
+		s.Require().NoError(err)
+		s.Require().NoError(os.Chtimes(path.Join(dir, file.name), modTime, modTime))
+	}
+	from, err := time.Parse(dateTimeFormat, "03/Mar/2022:02:43:40 +0000")
+	s.Require().NoError(err)
+	warnings := &bytes.Buffer{}
+	buf := &bytes.Buffer{}
+	reader, err := NewReader(ReaderConfig{
+		Directory: dir,
+		From:      from,
+		Order:     OrderContent,
+		Logger:    log.New(warnings, "", 0),
+	})
+	s.Require().NoError(err)
+
+	err = reader.Read(context.Background(), buf)
+
+	var expectedLogs string
+	for _, t := range []string{"02:43:40", "02:44:00", "02:45:00", "02:45:20", "02:45:40"} {
+		expectedLogs += fmt.Sprintf("127.0.0.1 user-identifier frank [03/Mar/2022:%s +0000] \"GET /api/endpoint HTTP/1.0\" 500 123\n", t)
+	}
+	s.NoError(err)
+	s.Equal(expectedLogs, buf.String())
+	s.Equal(`warning: file 'http-3.log' was modified at 2022-03-03 02:45:30 +0000 UTC, before its last log at 2022-03-03 02:45:40 +0000 UTC
+warning: file 'http-3.log' is older than 'http-2.log' by modification time, but newer by content
+`, warnings.String())
+}
+
+func (s *readerSuite) Test_NewReader_UnknownOrder() {
+	reader, err := NewReader(ReaderConfig{
+		Directory: testDataDir,
+		Order:     "size",
+	})
+
+	s.EqualError(err, "order 'size': unknown order")
+	s.Nil(reader)
+}
+
+func (s *readerSuite) Test_Read_Merge() {
+	dir := "test/merge"
+	s.Require().NoError(os.MkdirAll(dir, 0777))
+	defer func() {
+		s.Require().NoError(os.RemoveAll(dir))
+	}()
+	// load balanced hosts logging at the same time, the logs of web-3 were rotated and compressed
+	files := []struct {
+		name       string
+		logs       []string
+		compressed bool
+	}{
+		{name: "web-1.log", logs: []string{"02:41:00", "02:41:30", "02:42:00", "02:44:00"}},
+		{name: "web-2.log", logs: []string{"02:41:10", "02:41:30", "02:43:00"}},
+		{name: "web-3.log.gz", logs: []string{"02:40:50", "02:41:20", "02:42:10", "02:45:00"}, compressed: true},
+	}
+	for _, file := range files {
+		var logs string
+		for _, t := range file.logs {
+			logs += fmt.Sprintf("127.0.0.1 %s frank [03/Mar/2022:%s +0000] \"GET /api/endpoint HTTP/1.0\" 500 123\n", file.name, t)
+		}
+		f := s.createLogFile(dir, file.name, "")
+		if file.compressed {
+			gz := gzip.NewWriter(f)
+			_, err := gz.Write([]byte(logs))
+			s.Require().NoError(err)
+			s.Require().NoError(gz.Close())
+		} else {
+			_, err := f.WriteString(logs)
+			s.Require().NoError(err)
+		}
+		s.Require().NoError(f.Close())
+	}
+	from, err := time.Parse(dateTimeFormat, "03/Mar/2022:02:41:00 +0000")
+	s.Require().NoError(err)
+	to, err := time.Parse(dateTimeFormat, "03/Mar/2022:02:44:00 +0000")
+	s.Require().NoError(err)
+	buf := &bytes.Buffer{}
+	reader, err := NewReader(ReaderConfig{
+		Directory: dir,
+		From:      from,
+		To:        to,
+		Merge:     true,
+	})
+	s.Require().NoError(err)
+
+	err = reader.Read(context.Background(), buf)
+
+	expected := []struct {
+		name string
+		time string
+	}{
+		{"web-1.log", "02:41:00"},
+		{"web-2.log", "02:41:10"},
+		{"web-3.log.gz", "02:41:20"},
+		{"web-1.log", "02:41:30"},
+		{"web-2.log", "02:41:30"},
+		{"web-1.log", "02:42:00"},
+		{"web-3.log.gz", "02:42:10"},
+		{"web-2.log", "02:43:00"},
+		{"web-1.log", "02:44:00"},
+	}
+	var expectedLogs string
+	for _, log := range expected {
+		expectedLogs += fmt.Sprintf("127.0.0.1 %s frank [03/Mar/2022:%s +0000] \"GET /api/endpoint HTTP/1.0\" 500 123\n", log.name, log.time)
+	}
+	s.NoError(err)
+	s.Equal(expectedLogs, buf.String())
+}
+
+func (s *readerSuite) Test_NewReader_FollowWithMerge() {
+	reader, err := NewReader(ReaderConfig{
+		Directory: testDataDir,
+		Follow:    true,
+		Merge:     true,
+	})
+
+	s.EqualError(err, "follow with merge: unsupported mode")
+	s.Nil(reader)
+}
+
+func (s *readerSuite) Test_Read_Filter() {
+	dir := "test/filter"
+	s.Require().NoError(os.MkdirAll(dir, 0777))
+	defer func() {
+		s.Require().NoError(os.RemoveAll(dir))
+	}()
+	logs := `10.0.0.1 - frank [03/Mar/2022:02:41:40 +0000] "POST /api/users HTTP/1.0" 500 123
+192.168.1.1 - frank [03/Mar/2022:02:41:50 +0000] "POST /api/users HTTP/1.0" 503 123
+10.0.0.2 - frank [03/Mar/2022:02:42:00 +0000] "GET /api/users HTTP/1.0" 500 123
+10.0.0.3 - frank [03/Mar/2022:02:42:10 +0000] "POST /login HTTP/1.0" 502 123
+10.0.0.4 - frank [03/Mar/2022:02:42:20 +0000] "POST /api/orders HTTP/1.0" 504 123
+10.0.0.5 - frank [03/Mar/2022:02:42:30 +0000] "POST /api/orders HTTP/1.0" 201 123
+`
+	s.Require().NoError(s.createLogFile(dir, "http.log", logs).Close())
+	filter, err := ParseFilter(`status >= 500 && method == "POST" && path =~ "^/api/" && ip in 10.0.0.0/8`)
+	s.Require().NoError(err)
+	from, err := time.Parse(dateTimeFormat, "03/Mar/2022:02:41:00 +0000")
+	s.Require().NoError(err)
+	buf := &bytes.Buffer{}
+	reader, err := NewReader(ReaderConfig{
+		Directory: dir,
+		From:      from,
+		Filter:    filter,
+	})
+	s.Require().NoError(err)
+
+	err = reader.Read(context.Background(), buf)
+
+	s.NoError(err)
+	s.Equal(`10.0.0.1 - frank [03/Mar/2022:02:41:40 +0000] "POST /api/users HTTP/1.0" 500 123
+10.0.0.4 - frank [03/Mar/2022:02:42:20 +0000] "POST /api/orders HTTP/1.0" 504 123
+`, buf.String())
+}
+
+func (s *readerSuite) Test_Read_LongLines() {
+	dir := "test/long-lines"
+	s.Require().NoError(os.MkdirAll(dir, 0777))
+	defer func() {
+		s.Require().NoError(os.RemoveAll(dir))
+	}()
+	line := func(t, referer string) string {
+		return fmt.Sprintf(`10.0.0.1 - frank [03/Mar/2022:02:%s +0000] "GET /api/users HTTP/1.0" 200 123 "%s" "curl"`, t, referer)
+	}
+	long := line("42:00", strings.Repeat("a", 100*1024))
+	tooLong := line("42:10", strings.Repeat("b", 200*1024))
+	s.Require().NoError(s.createLogFile(dir, "http-1.log", line("41:50", "-")+"\n").Close())
+	s.Require().NoError(s.createLogFile(dir, "http-2.log", long+"\n"+tooLong+"\n"+line("42:20", "-")+"\n").Close())
+	s.Require().NoError(os.Chtimes(path.Join(dir, "http-1.log"), s.nowFunc().Add(-time.Minute), s.nowFunc().Add(-time.Minute)))
+	s.Require().NoError(os.Chtimes(path.Join(dir, "http-2.log"), s.nowFunc(), s.nowFunc()))
+	from, err := time.Parse(dateTimeFormat, "03/Mar/2022:02:41:00 +0000")
+	s.Require().NoError(err)
+
+	tests := []struct {
+		name             string
+		longLines        string
+		expectedLogs     string
+		expectedWarnings string
+		expectedError    string
+	}{
+		{
+			name:          "Fail",
+			longLines:     LongLinesFail,
+			expectedLogs:  line("41:50", "-") + "\n" + long + "\n",
+			expectedError: fmt.Sprintf("file 'http-2.log': offset %d: longer than 131072 bytes: line too long", len(long)+1),
+		},
+		{
+			name:         "Skip",
+			longLines:    LongLinesSkip,
+			expectedLogs: line("41:50", "-") + "\n" + long + "\n" + line("42:20", "-") + "\n",
+			expectedWarnings: fmt.Sprintf("warning: skipping line of 'http-2.log': offset %d: longer than 131072 bytes: line too long\n", len(long)+1) +
+				"warning: skipped 1 lines longer than 131072 bytes\n",
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			buf, warnings := &bytes.Buffer{}, &bytes.Buffer{}
+			reader, err := NewReader(ReaderConfig{
+				Directory:   dir,
+				From:        from,
+				MaxLineSize: 128 * 1024,
+				LongLines:   test.longLines,
+				Logger:      log.New(warnings, "", 0),
+			})
+			s.Require().NoError(err)
+
+			err = reader.Read(context.Background(), buf)
+
+			if test.expectedError == "" {
+				s.NoError(err)
+			} else {
+				s.EqualError(err, test.expectedError)
+			}
+			s.Equal(test.expectedLogs, buf.String())
+			s.Equal(test.expectedWarnings, warnings.String())
+		})
+	}
+}
+
+func (s *readerSuite) Test_NewReader_UnknownLongLinesPolicy() {
+	reader, err := NewReader(ReaderConfig{
+		Directory: testDataDir,
+		LongLines: "truncate",
+	})
+
+	s.EqualError(err, "long lines 'truncate': unknown policy")
+	s.Nil(reader)
+}
+
+func (s *readerSuite) Test_ReadEntries_Malformed() {
+	dir := "test/malformed"
+	s.Require().NoError(os.MkdirAll(dir, 0777))
+	defer func() {
+		s.Require().NoError(os.RemoveAll(dir))
+	}()
+	partial, junk := "127.0.0.1 - frank [03/Mar/2022:02:4", "\x00\x01\x02 binary junk"
+	logs := `127.0.0.1 - frank [03/Mar/2022:02:41:40 +0000] "GET /a HTTP/1.0" 200 123
+127.0.0.1 - frank [03/Mar/2022:02:41:50 +0000] "GET /b HTTP/1.0" 200 123
+` + partial + "\n" + junk + "\n" + `127.0.0.1 - frank [03/Mar/2022:02:42:10 +0000] "GET /c HTTP/1.0" 200 123
+127.0.0.1 - frank [03/Mar/2022:02:42:20 +0000] "GET /d HTTP/1.0" 200 123
+`
+	s.Require().NoError(s.createLogFile(dir, "http.log", logs).Close())
+	from, err := time.Parse(dateTimeFormat, "03/Mar/2022:02:41:45 +0000")
+	s.Require().NoError(err)
+
+	tests := []struct {
+		name               string
+		malformed          string
+		expectedPaths      []string
+		expectedSkipped    Skipped
+		expectedQuarantine string
+		expectedWarnings   string
+		expectedError      string
+	}{
+		{
+			name:      "Fail",
+			malformed: MalformedFail,
+			// the binary search lands on a malformed line
+			expectedError: "line '" + partial + "': invalid log format",
+		},
+		{
+			name:             "Skip",
+			malformed:        MalformedSkip,
+			expectedPaths:    []string{"/b", "/c", "/d"},
+			expectedSkipped:  Skipped{Malformed: 2},
+			expectedWarnings: "warning: skipped 2 malformed lines\n",
+		},
+		{
+			name:               "Quarantine",
+			malformed:          MalformedQuarantine,
+			expectedPaths:      []string{"/b", "/c", "/d"},
+			expectedSkipped:    Skipped{Malformed: 2},
+			expectedQuarantine: partial + "\n" + junk + "\n",
+			expectedWarnings:   "warning: skipped 2 malformed lines\n",
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			quarantine, warnings := &bytes.Buffer{}, &bytes.Buffer{}
+			reader, err := NewReader(ReaderConfig{
+				Directory:  dir,
+				From:       from,
+				Malformed:  test.malformed,
+				Quarantine: quarantine,
+				Logger:     log.New(warnings, "", 0),
+			})
+			s.Require().NoError(err)
+
+			var paths []string
+			err = reader.ReadEntries(context.Background(), func(entry Entry) error {
+				paths = append(paths, entry.Path)
+				return nil
+			})
+
+			if test.expectedError == "" {
+				s.NoError(err)
+			} else {
+				s.EqualError(err, test.expectedError)
+			}
+			s.Equal(test.expectedPaths, paths)
+			s.Equal(test.expectedSkipped, reader.Skipped())
+			s.Equal(test.expectedQuarantine, quarantine.String())
+			s.Equal(test.expectedWarnings, warnings.String())
+		})
+	}
+}
+
+func (s *readerSuite) Test_Read_Malformed() {
+	dir := "test/malformed"
+	s.Require().NoError(os.MkdirAll(dir, 0777))
+	defer func() {
+		s.Require().NoError(os.RemoveAll(dir))
+	}()
+	junk := "\x00\x01\x02 binary junk"
+	logs := `127.0.0.1 - frank [03/Mar/2022:02:41:40 +0000] "GET /a HTTP/1.0" 200 123
+127.0.0.1 - frank [03/Mar/2022:02:41:50 +0000] "GET /b HTTP/1.0" 200 123
+127.0.0.1 - frank [03/Mar/2022:02:42:00 +0000] "GET /c HTTP/1.0" 200 123
+` + junk + "\n" + `127.0.0.1 - frank [03/Mar/2022:02:42:20 +0000] "GET /d HTTP/1.0" 200 123
+`
+	s.Require().NoError(s.createLogFile(dir, "http.log", logs).Close())
+	from, err := time.Parse(dateTimeFormat, "03/Mar/2022:02:41:45 +0000")
+	s.Require().NoError(err)
+	window := strings.SplitAfterN(logs, "\n", 2)[1]
+
+	tests := []struct {
+		name               string
+		malformed          string
+		expectedOutput     string
+		expectedSkipped    Skipped
+		expectedQuarantine string
+		expectedError      string
+	}{
+		{
+			name: "No Policy",
+			// the lines are printed as they are, without being parsed
+			expectedOutput: window,
+		},
+		{
+			name:           "Fail",
+			malformed:      MalformedFail,
+			expectedOutput: strings.Split(window, junk)[0],
+			expectedError:  "line '" + junk + "': invalid log format",
+		},
+		{
+			name:            "Skip",
+			malformed:       MalformedSkip,
+			expectedOutput:  strings.Replace(window, junk+"\n", "", 1),
+			expectedSkipped: Skipped{Malformed: 1},
+		},
+		{
+			name:               "Quarantine",
+			malformed:          MalformedQuarantine,
+			expectedOutput:     strings.Replace(window, junk+"\n", "", 1),
+			expectedSkipped:    Skipped{Malformed: 1},
+			expectedQuarantine: junk + "\n",
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			output, quarantine := &bytes.Buffer{}, &bytes.Buffer{}
+			reader, err := NewReader(ReaderConfig{
+				Directory:  dir,
+				From:       from,
+				Malformed:  test.malformed,
+				Quarantine: quarantine,
+			})
+			s.Require().NoError(err)
+
+			err = reader.Read(context.Background(), output)
+
+			if test.expectedError == "" {
+				s.NoError(err)
+			} else {
+				s.EqualError(err, test.expectedError)
+			}
+			s.Equal(test.expectedOutput, output.String())
+			s.Equal(test.expectedSkipped, reader.Skipped())
+			s.Equal(test.expectedQuarantine, quarantine.String())
+		})
+	}
+}
+
+func (s *readerSuite) Test_NewReader_MalformedError() {
+	tests := []struct {
+		name          string
+		cfg           ReaderConfig
+		expectedError string
+	}{
+		{
+			name:          "Unknown Policy",
+			cfg:           ReaderConfig{Directory: testDataDir, Malformed: "ignore"},
+			expectedError: "malformed 'ignore': unknown policy",
+		},
+		{
+			name:          "Quarantine Without Writer",
+			cfg:           ReaderConfig{Directory: testDataDir, Malformed: MalformedQuarantine},
+			expectedError: "quarantine without a writer: unsupported mode",
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			reader, err := NewReader(test.cfg)
+
+			s.EqualError(err, test.expectedError)
+			s.Nil(reader)
+		})
+	}
+}
+
+func (s *readerSuite) Test_ReadEntries_Success() {
+	ctx := context.Background()
+	cfg := ReaderConfig{
+		Directory: testDataDir,
+		Since:     90 * time.Second,
+	}
+	reader, err := NewReader(cfg)
+	s.Require().NoError(err)
+	reader.nowFunc = s.nowFunc
+	var times []string
+
+	err = reader.ReadEntries(ctx, func(entry Entry) error {
+		s.Equal("127.0.0.1", entry.RemoteHost)
+		s.Equal("/api/endpoint", entry.Path)
+		s.Equal(500, entry.Status)
+		times = append(times, entry.Time.Format(dateTimeFormat))
+		return nil
+	})
+
+	s.NoError(err)
+	s.Equal([]string{
+		"03/Mar/2022:02:43:40 +0000",
+		"03/Mar/2022:02:44:00 +0000",
+		"03/Mar/2022:02:45:00 +0000",
+		"03/Mar/2022:02:45:20 +0000",
+		"03/Mar/2022:02:45:40 +0000",
+	}, times)
+}
+
+func (s *readerSuite) Test_ReadEntries_Error() {
+	ctx := context.Background()
+	cfg := ReaderConfig{
+		Directory: testDataDir,
+		Since:     90 * time.Second,
+	}
+	reader, err := NewReader(cfg)
+	s.Require().NoError(err)
+	reader.nowFunc = s.nowFunc
+	calls := 0
+
+	err = reader.ReadEntries(ctx, func(entry Entry) error {
+		calls++
+		return errors.New("stop")
+	})
+
+	s.EqualError(err, "stop")
+	s.Equal(1, calls)
+}
+
 func (s *readerSuite) Test_Read_OpenError() {
 	ctx := context.Background()
 	buf := &bytes.Buffer{}
@@ -220,6 +903,119 @@ func (s *readerSuite) Test_Read_IndexTimeError() {
 	s.Equal("", buf.String())
 }
 
+func (s *readerSuite) Test_ReadEntries_NoLeaks() {
+	errEmit := errors.New("emit error")
+	tests := []struct {
+		name          string
+		cfg           ReaderConfig
+		stopAt        int
+		cancel        bool
+		expectedCount int
+		expectedError error
+	}{
+		{
+			name:          "Emit Error While Streaming",
+			cfg:           ReaderConfig{Directory: testDataDir, Since: 90 * time.Second},
+			stopAt:        3,
+			expectedCount: 3,
+			expectedError: errEmit,
+		},
+		{
+			name:          "Canceled While Searching",
+			cfg:           ReaderConfig{Directory: testDataDir, Since: 90 * time.Second},
+			stopAt:        0,
+			cancel:        true,
+			expectedCount: 0,
+		},
+		{
+			name:          "Canceled While Reading",
+			cfg:           ReaderConfig{Directory: testDataDir, Since: 90 * time.Second},
+			stopAt:        1,
+			cancel:        true,
+			expectedCount: 1,
+		},
+		{
+			name:          "Canceled While Streaming",
+			cfg:           ReaderConfig{Directory: testDataDir, Since: 90 * time.Second},
+			stopAt:        3,
+			cancel:        true,
+			expectedCount: 3,
+		},
+		{
+			name:          "Canceled While Merging",
+			cfg:           ReaderConfig{Directory: testDataDir, Since: 90 * time.Second, Merge: true},
+			stopAt:        2,
+			cancel:        true,
+			expectedCount: 2,
+		},
+		{
+			name:          "Canceled While Following",
+			cfg:           ReaderConfig{Directory: testDataDir, Since: 90 * time.Second, Follow: true, FollowInterval: time.Millisecond},
+			stopAt:        5,
+			cancel:        true,
+			expectedCount: 5,
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			s.assertNoLeaks(func() {
+				reader, err := NewReader(test.cfg)
+				s.Require().NoError(err)
+				reader.nowFunc = s.nowFunc
+				// the context is never canceled on emit errors, nothing else stops the reading then
+				ctx, cancel := context.Background(), context.CancelFunc(func() {})
+				if test.cancel {
+					ctx, cancel = context.WithCancel(ctx)
+					defer cancel()
+				}
+				if test.stopAt == 0 {
+					cancel()
+				}
+
+				count := 0
+				err = reader.ReadEntries(ctx, func(entry Entry) error {
+					count++
+					if count < test.stopAt {
+						return nil
+					}
+					if test.cancel {
+						cancel()
+						return nil
+					}
+					return errEmit
+				})
+
+				s.Equal(test.expectedError, err)
+				s.Equal(test.expectedCount, count)
+			})
+		})
+	}
+}
+
+// assertNoLeaks checks that fn leaves neither goroutines running nor files open behind
+func (s *readerSuite) assertNoLeaks(fn func()) {
+	goroutines, files := runtime.NumGoroutine(), s.openFiles()
+
+	fn()
+
+	// stopping goroutines may take a moment, testify's Eventually can't be used since it runs its own goroutines
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > goroutines && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	s.LessOrEqual(runtime.NumGoroutine(), goroutines, "leaked goroutines")
+	s.LessOrEqual(s.openFiles(), files, "leaked open files")
+}
+
+// openFiles counts the open file descriptors of the process
+func (s *readerSuite) openFiles() int {
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		s.T().Skip("open files can't be counted on this platform")
+	}
+	return len(fds)
+}
+
 func (s *readerSuite) createLogFile(dir, name, logs string) *os.File {
 	file, err := os.Create(path.Join(dir, name))
 	s.Require().NoError(err)
@@ -0,0 +1,188 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// reverse reads the logs of the time window newest first, walking the log files backwards from the newest one.
+// Each file is searched for the time window, then its lines are read backwards from the end of the window,
+// the reading stops once the beginning of the window was crossed or the limit was reached
+func (r *Reader) reverse(ctx context.Context, emit func(line Line) error) error {
+	from, to := r.window()
+	for i := len(r.filesInfo) - 1; i >= 0; i-- {
+		fi := r.filesInfo[i]
+		if fi.endsBefore(from) {
+			return nil
+		}
+
+		windowBegun, err := r.reverseFile(ctx, emit, fi, from, to)
+		if err != nil || windowBegun {
+			return err
+		}
+	}
+	return nil
+}
+
+// reverseFile reads the logs of the time window inside the given file, newest first.
+// windowBegun == true -> the window begins inside the log file, no need to read any older file
+func (r *Reader) reverseFile(ctx context.Context, emit func(line Line) error, fi fileInfo, from, to time.Time) (windowBegun bool, err error) {
+	f, err := os.Open(path.Join(r.cfg.Directory, fi.name))
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = f.Close() }()
+
+	// compressed files can't be read backwards, so the logs of the window are kept in memory and emitted in reverse
+	decompressed, compressed, err := decompress(f)
+	if err != nil {
+		return false, err
+	}
+	if compressed {
+		var lines []Line
+		keep := func(line Line) error {
+			lines = append(lines, line)
+			return nil
+		}
+		_, _, err := r.scan(ctx, keep, decompressed, fi.name, from, to)
+		if err != nil {
+			return false, err
+		}
+		for i := len(lines) - 1; i >= 0; i-- {
+			err := emit(lines[i])
+			if err != nil {
+				return false, err
+			}
+		}
+		return false, nil
+	}
+
+	file := r.newFile(f)
+	end, err := r.windowEnd(ctx, file, to)
+	if err != nil {
+		return false, err
+	}
+	start, err := file.indexTime(ctx, from)
+	if err != nil {
+		return false, err
+	}
+	if start < 0 {
+		return true, nil
+	}
+	// the window ends before the logs of the file newer than its beginning
+	if start >= end {
+		return start > 0, nil
+	}
+
+	lines := newReverseLineReader(ctx, f, start, end, r.maxLineSize(), r.tooLong(fi.name))
+	for {
+		line, offset, err := lines.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return false, err
+		}
+		err = emit(Line{Text: line, File: fi.name, Offset: offset})
+		if err != nil {
+			return false, err
+		}
+	}
+	return start > 0, nil
+}
+
+// newReverseLineReader creates a reverse line reader of the lines between the start and end offsets of the file.
+// Same as lineReader, tooLong decides what happens with the lines longer than max
+func newReverseLineReader(ctx context.Context, file io.ReaderAt, start, end int64, max int, tooLong func(err error) error) *reverseLineReader {
+	if max <= 0 {
+		max = DefaultMaxLineSize
+	}
+	return &reverseLineReader{
+		ctx:     ctx,
+		file:    file,
+		start:   start,
+		pos:     end,
+		max:     max,
+		tooLong: tooLong,
+	}
+}
+
+// reverseLineReader reads the lines of a file backwards, last line first.
+// It walks the file backwards in 32KB blocks, the same way seekLine does, keeping the lines instead of counting them,
+// at most max bytes of a line are kept in memory: longer lines are dropped as they are read
+type reverseLineReader struct {
+	ctx     context.Context
+	file    io.ReaderAt
+	max     int
+	tooLong func(err error) error
+	// start is the offset the reading stops at
+	start int64
+	// pending are the bytes read but not returned yet, starting at the pos offset
+	pending []byte
+	pos     int64
+	// dropping is set while reading a line that is too long
+	dropping bool
+}
+
+// next returns the previous line without its line ending alongside its offset,
+// io.EOF is returned once the start offset was reached
+func (l *reverseLineReader) next() (string, int64, error) {
+	const bufferSize = 32 * 1024 // 32KB
+	for {
+		if err := l.ctx.Err(); err != nil {
+			return "", 0, err
+		}
+
+		if len(l.pending) > 0 {
+			// the last pending byte is the line ending of the line being read, if any
+			i := bytes.LastIndexByte(l.pending[:len(l.pending)-1], '\n')
+			if i >= 0 || l.pos == l.start {
+				offset := l.pos + int64(i+1)
+				line := strings.TrimRight(string(l.pending[i+1:]), "\r\n")
+				l.pending = l.pending[:i+1]
+				if !l.dropping && len(line) <= l.max {
+					return line, offset, nil
+				}
+
+				l.dropping = false
+				err := fmt.Errorf("offset %d: longer than %d bytes: %w", offset, l.max, errLineTooLong)
+				if l.tooLong == nil {
+					return "", 0, err
+				}
+				err = l.tooLong(err)
+				if err != nil {
+					return "", 0, err
+				}
+				continue
+			}
+
+			// leave room for the line ending, then drop the line keeping a single byte,
+			// which stands for its line ending, so its beginning is still found
+			if len(l.pending) > l.max+2 {
+				l.dropping = true
+				l.pending = l.pending[:1]
+			}
+		}
+		if l.pos == l.start {
+			return "", 0, io.EOF
+		}
+
+		size := int64(bufferSize)
+		if l.pos-l.start < size {
+			size = l.pos - l.start
+		}
+		block := make([]byte, size, int(size)+len(l.pending))
+		_, err := l.file.ReadAt(block, l.pos-size)
+		if err != nil && err != io.EOF {
+			return "", 0, err
+		}
+		l.pending = append(block, l.pending...)
+		l.pos -= size
+	}
+}
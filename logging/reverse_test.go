@@ -0,0 +1,264 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type reverseSuite struct {
+	suite.Suite
+	dir   string
+	start time.Time
+}
+
+func (s *reverseSuite) SetupTest() {
+	start, err := time.Parse(dateTimeFormat, "03/Mar/2022:02:00:00 +0000")
+	s.Require().NoError(err)
+	s.start = start
+	s.dir = s.T().TempDir()
+	writeTestLogs(s.T(), path.Join(s.dir, "http-1.log"), testLogs(s.start, 0, 10), s.start.Add(time.Minute))
+	writeTestLogs(s.T(), path.Join(s.dir, "http-2.log"), testLogs(s.start, 10, 20), s.start.Add(2*time.Minute))
+}
+
+func (s *reverseSuite) Test_Read_Reverse() {
+	tests := []struct {
+		name          string
+		cfg           ReaderConfig
+		expectedPaths []string
+	}{
+		{
+			name:          "Time Window",
+			cfg:           ReaderConfig{From: s.start.Add(7 * time.Second), To: s.start.Add(12 * time.Second)},
+			expectedPaths: []string{"/12", "/11", "/10", "/9", "/8", "/7"},
+		},
+		{
+			name:          "Limit",
+			cfg:           ReaderConfig{From: s.start, Limit: 3},
+			expectedPaths: []string{"/19", "/18", "/17"},
+		},
+		{
+			name:          "Limit Across Files",
+			cfg:           ReaderConfig{From: s.start, Limit: 12},
+			expectedPaths: []string{"/19", "/18", "/17", "/16", "/15", "/14", "/13", "/12", "/11", "/10", "/9", "/8"},
+		},
+		{
+			name:          "Limit With Filter",
+			cfg:           ReaderConfig{From: s.start, Limit: 3, Filter: s.filter("status == 500")},
+			expectedPaths: []string{"/15", "/10", "/5"},
+		},
+		{
+			name:          "Window Beginning",
+			cfg:           ReaderConfig{From: s.start.Add(17 * time.Second)},
+			expectedPaths: []string{"/19", "/18", "/17"},
+		},
+		{
+			name:          "Window Ending Before The Newest File",
+			cfg:           ReaderConfig{From: s.start, To: s.start.Add(5 * time.Second), Limit: 2},
+			expectedPaths: []string{"/5", "/4"},
+		},
+		{
+			name: "Outside Time Window",
+			cfg:  ReaderConfig{From: s.start.Add(time.Hour)},
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			test.cfg.Directory = s.dir
+			test.cfg.Reverse = true
+
+			s.Equal(test.expectedPaths, s.read(test.cfg))
+		})
+	}
+}
+
+func (s *reverseSuite) Test_Read_ReverseCompressed() {
+	archive := &bytes.Buffer{}
+	_, err := Compress(archive, strings.NewReader(testLogs(s.start, 0, 10)), NewApacheParser(), 0)
+	s.Require().NoError(err)
+	s.Require().NoError(os.Remove(path.Join(s.dir, "http-1.log")))
+	writeTestLogs(s.T(), path.Join(s.dir, "http-1.log.gz"), archive.String(), s.start.Add(time.Minute))
+
+	paths := s.read(ReaderConfig{
+		Directory: s.dir,
+		From:      s.start.Add(7 * time.Second),
+		Reverse:   true,
+		Limit:     5,
+	})
+
+	s.Equal([]string{"/19", "/18", "/17", "/16", "/15"}, paths)
+	paths = s.read(ReaderConfig{
+		Directory: s.dir,
+		From:      s.start.Add(7 * time.Second),
+		To:        s.start.Add(12 * time.Second),
+		Reverse:   true,
+	})
+	s.Equal([]string{"/12", "/11", "/10", "/9", "/8", "/7"}, paths)
+}
+
+func (s *reverseSuite) Test_Read_Limit() {
+	paths := s.read(ReaderConfig{
+		Directory: s.dir,
+		From:      s.start.Add(8 * time.Second),
+		Limit:     3,
+	})
+
+	s.Equal([]string{"/8", "/9", "/10"}, paths)
+}
+
+func (s *reverseSuite) Test_NewReader_ReverseUnsupported() {
+	tests := []struct {
+		name string
+		cfg  ReaderConfig
+	}{
+		{
+			name: "Follow",
+			cfg:  ReaderConfig{Follow: true},
+		},
+		{
+			name: "Merge",
+			cfg:  ReaderConfig{Merge: true},
+		},
+		{
+			name: "Checkpoint",
+			cfg:  ReaderConfig{Checkpoint: &Checkpoint{}},
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			test.cfg.Directory = s.dir
+			test.cfg.Reverse = true
+
+			reader, err := NewReader(test.cfg)
+
+			s.Nil(reader)
+			s.EqualError(err, "reverse with follow, merge or checkpoint: unsupported mode")
+		})
+	}
+}
+
+func (s *reverseSuite) Test_ReverseLineReader() {
+	long := strings.Repeat("x", 40*1024)
+	tests := []struct {
+		name          string
+		content       string
+		start         int64
+		max           int
+		skip          bool
+		expectedLines []string
+		expectedError string
+	}{
+		{
+			name:          "Lines",
+			content:       "a\nbb\n\nccc\n",
+			expectedLines: []string{"ccc", "", "bb", "a"},
+		},
+		{
+			name:          "No Trailing Line Ending",
+			content:       "a\r\nbb\r\nccc",
+			expectedLines: []string{"ccc", "bb", "a"},
+		},
+		{
+			name:          "Start Offset",
+			content:       "a\nbb\nccc\n",
+			start:         2,
+			expectedLines: []string{"ccc", "bb"},
+		},
+		{
+			name:          "Lines Across Blocks",
+			content:       "a\n" + long + "\nb\n",
+			expectedLines: []string{"b", long, "a"},
+		},
+		{
+			name:          "Skip Long Lines",
+			content:       "a\n" + long + "\nb\n" + long + "\n",
+			max:           1024,
+			skip:          true,
+			expectedLines: []string{"b", "a"},
+		},
+		{
+			name:          "Long Line",
+			content:       "a\n" + long + "\nb\n",
+			max:           1024,
+			expectedLines: []string{"b"},
+			expectedError: "offset 2: longer than 1024 bytes: line too long",
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			var tooLong func(err error) error
+			if test.skip {
+				tooLong = func(error) error {
+					return nil
+				}
+			}
+			lines := newReverseLineReader(context.Background(), strings.NewReader(test.content), test.start, int64(len(test.content)), test.max, tooLong)
+			var actual []string
+			var err error
+
+			for {
+				var line string
+				line, _, err = lines.next()
+				if err != nil {
+					break
+				}
+				actual = append(actual, line)
+			}
+
+			s.Equal(test.expectedLines, actual)
+			if test.expectedError == "" {
+				s.Equal(io.EOF, err)
+			} else {
+				s.EqualError(err, test.expectedError)
+			}
+		})
+	}
+}
+
+func (s *reverseSuite) Test_ReverseLineReader_Offsets() {
+	content := "a\nbb\nccc\n"
+	lines := newReverseLineReader(context.Background(), strings.NewReader(content), 0, int64(len(content)), 0, nil)
+	var offsets []int64
+
+	for {
+		_, offset, err := lines.next()
+		if err != nil {
+			s.Equal(io.EOF, err)
+			break
+		}
+		offsets = append(offsets, offset)
+	}
+
+	s.Equal([]int64{5, 2, 0}, offsets)
+}
+
+func (s *reverseSuite) read(cfg ReaderConfig) []string {
+	reader, err := NewReader(cfg)
+	s.Require().NoError(err)
+	var paths []string
+
+	err = reader.ReadEntries(context.Background(), func(entry Entry) error {
+		paths = append(paths, entry.Path)
+		return nil
+	})
+
+	s.Require().NoError(err)
+	return paths
+}
+
+func (s *reverseSuite) filter(expr string) *Filter {
+	filter, err := ParseFilter(expr)
+	s.Require().NoError(err)
+	return filter
+}
+
+func TestReverse(t *testing.T) {
+	suite.Run(t, new(reverseSuite))
+}
@@ -0,0 +1,255 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/steevehook/weblog-analytics/logging"
+)
+
+// DefaultLiveBuffer is the default number of entries buffered for every live client
+const DefaultLiveBuffer = 256
+
+// liveHeartbeat is how often idle live clients receive a comment, so proxies don't close the connection
+const liveHeartbeat = 15 * time.Second
+
+// live pushes the new logs as Server-Sent Events, after replaying the logs of the time window if one was given.
+// Every client has its own buffer, the entries that don't fit because the client is too slow are dropped
+// instead of stalling the tailer, and the client is told how many entries it missed with a dropped event
+func (s *Server) live(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+
+	query := r.URL.Query()
+	cfg, err := s.readerConfig(query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if to := query.Get("to"); to != "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("to '%s': %w", to, errInvalidParameter))
+		return
+	}
+	buffer := DefaultLiveBuffer
+	if value := query.Get("buffer"); value != "" {
+		buffer, err = strconv.Atoi(value)
+		if err != nil || buffer <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("buffer '%s': %w", value, errInvalidParameter))
+			return
+		}
+	}
+
+	// the logs up till now are replayed, the ones after are pushed live
+	c := &client{
+		filter:  cfg.Filter,
+		since:   time.Now().UTC(),
+		entries: make(chan logging.Entry, buffer),
+	}
+	var replay *logging.Reader
+	if query.Get("since") != "" || query.Get("from") != "" {
+		cfg.To = c.since
+		replay, err = logging.NewReader(cfg)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	err = s.hub.subscribe(c)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer s.hub.unsubscribe(c)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ctx := r.Context()
+	if replay != nil {
+		err = replay.ReadEntries(ctx, func(entry logging.Entry) error {
+			return writeEvent(w, "", entry)
+		})
+		if err != nil {
+			if ctx.Err() == nil {
+				s.logf("could not replay logs: %v", err)
+			}
+			return
+		}
+		flusher.Flush()
+	}
+
+	heartbeat := time.NewTicker(liveHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			_, err = io.WriteString(w, ": heartbeat\n\n")
+		case entry, ok := <-c.entries:
+			if !ok {
+				return
+			}
+			if dropped := atomic.SwapInt64(&c.dropped, 0); dropped > 0 {
+				err = writeEvent(w, "dropped", map[string]int64{"dropped": dropped})
+			}
+			if err == nil {
+				err = writeEvent(w, "", entry)
+			}
+		}
+		if err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes a Server-Sent Event with a JSON payload, the event name is omitted for the new logs
+func writeEvent(w io.Writer, event string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if event != "" {
+		_, err = fmt.Fprintf(w, "event: %s\n", event)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+	return err
+}
+
+// client represents a live client, receiving the new logs matching its filter
+type client struct {
+	filter *logging.Filter
+	// since is when the client subscribed, the logs before are replayed instead of pushed.
+	// Log times have a precision of a second, so the logs of that second may be both replayed and pushed
+	since   time.Time
+	entries chan logging.Entry
+	// dropped is the number of entries that didn't fit into the buffer since the last push
+	dropped int64
+}
+
+// hub follows the logs on behalf of all the live clients, broadcasting every new log to all of them.
+// It starts following with the first client and stops with the last one
+type hub struct {
+	cfg  logging.ReaderConfig
+	logf func(format string, v ...interface{})
+
+	mu      sync.Mutex
+	clients map[*client]struct{}
+	// cancel stops the tailer, it's nil when nothing is followed
+	cancel context.CancelFunc
+}
+
+func newHub(cfg logging.ReaderConfig, logf func(format string, v ...interface{})) *hub {
+	// the tailer starts at the end of the logs and keeps every log, the clients filter them on their own
+	cfg.Since, cfg.From, cfg.To = 0, time.Time{}, time.Time{}
+	cfg.Filter = nil
+	cfg.Merge = false
+	cfg.Follow = true
+	// a single malformed line would disconnect all the clients otherwise
+	if cfg.Malformed == "" || cfg.Malformed == logging.MalformedFail {
+		cfg.Malformed = logging.MalformedSkip
+	}
+
+	return &hub{
+		cfg:     cfg,
+		logf:    logf,
+		clients: map[*client]struct{}{},
+	}
+}
+
+func (h *hub) subscribe(c *client) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.cancel == nil {
+		reader, err := logging.NewReader(h.cfg)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		h.cancel = cancel
+		go h.run(ctx, reader)
+	}
+	h.clients[c] = struct{}{}
+	return nil
+}
+
+func (h *hub) unsubscribe(c *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.clients[c]; !ok {
+		return
+	}
+	delete(h.clients, c)
+	if len(h.clients) == 0 {
+		h.cancel()
+		h.cancel = nil
+	}
+}
+
+// run follows the logs till the last client is gone. If following fails, all the clients are disconnected,
+// so they can reconnect, replaying what they missed, and start a new tailer
+func (h *hub) run(ctx context.Context, reader *logging.Reader) {
+	err := reader.ReadEntries(ctx, func(entry logging.Entry) error {
+		h.broadcast(ctx, entry)
+		return nil
+	})
+	if err != nil {
+		h.logf("could not follow logs: %v", err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// a canceled tailer has no clients left, they may belong to a newer tailer already
+	if ctx.Err() != nil {
+		return
+	}
+	for c := range h.clients {
+		close(c.entries)
+		delete(h.clients, c)
+	}
+	h.cancel()
+	h.cancel = nil
+}
+
+// broadcast hands the entry to every client without ever blocking on a slow one
+func (h *hub) broadcast(ctx context.Context, entry logging.Entry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if ctx.Err() != nil {
+		return
+	}
+
+	for c := range h.clients {
+		if entry.Time.Before(c.since.Truncate(time.Second)) {
+			continue
+		}
+		if c.filter != nil && !c.filter.Match(entry) {
+			continue
+		}
+		select {
+		case c.entries <- entry:
+		default:
+			atomic.AddInt64(&c.dropped, 1)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/steevehook/weblog-analytics/logging"
+)
+
+type liveSuite struct {
+	suite.Suite
+	logFile    string
+	server     *Server
+	httpServer *httptest.Server
+}
+
+func (s *liveSuite) SetupTest() {
+	dir := s.T().TempDir()
+	s.logFile = path.Join(dir, "http.log")
+	s.Require().NoError(os.WriteFile(s.logFile, []byte(testLogs), 0644))
+	s.server = New(Config{
+		Reader: logging.ReaderConfig{
+			Directory:      dir,
+			FollowInterval: 10 * time.Millisecond,
+		},
+	})
+	s.httpServer = httptest.NewServer(s.server)
+}
+
+func (s *liveSuite) TearDownTest() {
+	s.httpServer.Close()
+}
+
+func (s *liveSuite) Test_Live_ReplayAndPush() {
+	resp, err := http.Get(s.httpServer.URL + "/live?from=2022-03-03T02:41:45Z&filter=status%20%3E=%20300")
+	s.Require().NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+	s.Equal("text/event-stream", resp.Header.Get("Content-Type"))
+	events := bufio.NewReader(resp.Body)
+
+	s.Contains(s.nextEvent(events), `"status":500`)
+	s.Contains(s.nextEvent(events), `"status":302`)
+
+	// the new logs are logged after the client subscribed, hence in the future
+	now := time.Now().UTC().Add(time.Hour).Format("02/Jan/2006:15:04:05 -0700")
+	s.appendLogs(
+		fmt.Sprintf(`10.0.0.3 - - [%s] "GET /ok HTTP/1.0" 200 1`, now),
+		fmt.Sprintf(`10.0.0.3 - - [%s] "GET /missing HTTP/1.0" 404 1`, now),
+	)
+
+	event := s.nextEvent(events)
+	s.Contains(event, `"path":"/missing"`)
+	s.True(strings.HasPrefix(event, "data: "))
+}
+
+func (s *liveSuite) Test_Live_Error() {
+	tests := []struct {
+		name         string
+		query        string
+		expectedBody string
+	}{
+		{
+			name:         "To",
+			query:        "to=2022-03-03T02:00:00Z",
+			expectedBody: `{"error":"to '2022-03-03T02:00:00Z': invalid parameter"}`,
+		},
+		{
+			name:         "Invalid Buffer",
+			query:        "buffer=0",
+			expectedBody: `{"error":"buffer '0': invalid parameter"}`,
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			req := httptest.NewRequest(http.MethodGet, "/live?"+test.query, nil)
+			rec := httptest.NewRecorder()
+
+			s.server.ServeHTTP(rec, req)
+
+			s.Equal(http.StatusBadRequest, rec.Code)
+			s.JSONEq(test.expectedBody, rec.Body.String())
+		})
+	}
+}
+
+func (s *liveSuite) Test_Hub_Backpressure() {
+	h := s.server.hub
+	slow := &client{entries: make(chan logging.Entry, 1)}
+	fast := &client{entries: make(chan logging.Entry, 10)}
+	s.Require().NoError(h.subscribe(slow))
+	s.Require().NoError(h.subscribe(fast))
+	defer h.unsubscribe(fast)
+	defer h.unsubscribe(slow)
+
+	for i := 0; i < 3; i++ {
+		h.broadcast(context.Background(), logging.Entry{Status: 200 + i})
+	}
+
+	s.Len(slow.entries, 1)
+	s.Equal(int64(2), slow.dropped)
+	s.Len(fast.entries, 3)
+	s.Equal(int64(0), fast.dropped)
+}
+
+func (s *liveSuite) Test_Hub_StopsWithLastClient() {
+	h := s.server.hub
+	first := &client{entries: make(chan logging.Entry, 1)}
+	second := &client{entries: make(chan logging.Entry, 1)}
+	s.Require().NoError(h.subscribe(first))
+	s.Require().NoError(h.subscribe(second))
+
+	h.unsubscribe(first)
+	s.NotNil(h.cancel)
+	h.unsubscribe(second)
+	s.Nil(h.cancel)
+	s.Empty(h.clients)
+}
+
+// nextEvent reads the next Server-Sent Event, skipping the comments
+func (s *liveSuite) nextEvent(r *bufio.Reader) string {
+	type result struct {
+		event string
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		var event strings.Builder
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				done <- result{err: err}
+				return
+			}
+			if strings.HasPrefix(line, ":") {
+				continue
+			}
+			if line == "\n" && event.Len() > 0 {
+				done <- result{event: event.String()}
+				return
+			}
+			event.WriteString(line)
+		}
+	}()
+
+	select {
+	case res := <-done:
+		s.Require().NoError(res.err)
+		return res.event
+	case <-time.After(5 * time.Second):
+		s.FailNow("timed out waiting for an event")
+		return ""
+	}
+}
+
+func (s *liveSuite) appendLogs(lines ...string) {
+	f, err := os.OpenFile(s.logFile, os.O_APPEND|os.O_WRONLY, 0644)
+	s.Require().NoError(err)
+	defer func() { _ = f.Close() }()
+	_, err = f.WriteString(strings.Join(lines, "\n") + "\n")
+	s.Require().NoError(err)
+}
+
+func TestLive(t *testing.T) {
+	suite.Run(t, new(liveSuite))
+}
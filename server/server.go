@@ -0,0 +1,256 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/steevehook/weblog-analytics/analytics"
+	"github.com/steevehook/weblog-analytics/logging"
+)
+
+var errInvalidParameter = errors.New("invalid parameter")
+
+// contentTypes are the content types of the logs output formats
+var contentTypes = map[string]string{
+	logging.OutputRaw:    "text/plain; charset=utf-8",
+	logging.OutputJSON:   "application/json",
+	logging.OutputNDJSON: "application/x-ndjson",
+	logging.OutputCSV:    "text/csv; charset=utf-8",
+}
+
+// Config represents the configuration of the HTTP query API
+type Config struct {
+	// Reader is the base configuration of every query: directory, parser, order, etc.
+	// The time window and the filter are overridden by the query parameters
+	Reader logging.ReaderConfig
+	// Logger receives the errors that happen after the response was already started
+	Logger *log.Logger
+}
+
+// New creates the HTTP query API handler exposing:
+// GET /logs?since=&from=&to=&filter=&format=&template= streaming the logs of the time window
+// GET /stats?since=&from=&to=&filter=&interval=&top=&approx= the aggregated stats of the time window
+// GET /live?since=&from=&filter=&buffer= replaying the logs of the time window, if any, then pushing the new logs as Server-Sent Events
+func New(cfg Config) *Server {
+	s := &Server{
+		cfg: cfg,
+		mux: http.NewServeMux(),
+	}
+	s.hub = newHub(cfg.Reader, s.logf)
+	s.mux.HandleFunc("/logs", s.logs)
+	s.mux.HandleFunc("/stats", s.stats)
+	s.mux.HandleFunc("/live", s.live)
+	return s
+}
+
+// Server represents the HTTP query API of the log reader
+type Server struct {
+	cfg Config
+	mux *http.ServeMux
+	hub *hub
+}
+
+// ServeHTTP serves the HTTP query API
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method))
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// logs streams the logs of the time window as they are read,
+// the reading stops as soon as the client goes away
+func (s *Server) logs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	cfg, err := s.readerConfig(query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	format := query.Get("format")
+	if format == "" {
+		format = logging.OutputNDJSON
+	}
+	contentType, ok := contentTypes[format]
+	if !ok {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("format '%s': %w", format, errInvalidParameter))
+		return
+	}
+
+	out := &flushWriter{ctx: r.Context(), w: w}
+	var writer logging.EntryWriter
+	switch {
+	case query.Get("template") != "":
+		contentType = contentTypes[logging.OutputRaw]
+		writer, err = logging.NewTemplateWriter(out, query.Get("template"))
+	case format != logging.OutputRaw:
+		writer, err = logging.NewEntryWriter(out, format)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	reader, err := logging.NewReader(cfg)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if writer == nil {
+		err = reader.Read(r.Context(), out)
+	} else {
+		err = reader.ReadEntries(r.Context(), writer.Write)
+		if err == nil {
+			err = writer.Close()
+		}
+	}
+	if err != nil && r.Context().Err() == nil {
+		s.logf("could not stream logs: %v", err)
+	}
+}
+
+// stats aggregates the logs of the time window and responds with the JSON report
+func (s *Server) stats(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	cfg, err := s.readerConfig(query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	interval, top, approx, err := statsParameters(query, cfg)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	reader, err := logging.NewReader(cfg)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	aggregator, err := analytics.Aggregate(r.Context(), reader, func() *analytics.Stats {
+		if approx {
+			return analytics.NewApproxStats(interval, analytics.DefaultCapacity)
+		}
+		return analytics.NewStats(interval)
+	})
+	if err != nil {
+		if r.Context().Err() == nil {
+			writeError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(aggregator.Report(top))
+	if err != nil {
+		s.logf("could not write stats: %v", err)
+	}
+}
+
+// readerConfig creates the reader configuration of a query out of its time window and filter parameters
+func (s *Server) readerConfig(query url.Values) (logging.ReaderConfig, error) {
+	cfg := s.cfg.Reader
+	if since := query.Get("since"); since != "" {
+		d, err := logging.ParseSince(since)
+		if err != nil {
+			return cfg, fmt.Errorf("since '%s': %w", since, errInvalidParameter)
+		}
+		// the query time window replaces the base one
+		cfg.Since, cfg.From = d, time.Time{}
+	}
+	for _, p := range []struct {
+		name  string
+		value *time.Time
+	}{{"from", &cfg.From}, {"to", &cfg.To}} {
+		value := query.Get(p.name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return cfg, fmt.Errorf("%s '%s': %w", p.name, value, errInvalidParameter)
+		}
+		*p.value = t
+	}
+	if filter := query.Get("filter"); filter != "" {
+		f, err := logging.ParseFilter(filter)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.Filter = f
+	}
+
+	// queries always end, the live endpoint is the one following the logs
+	cfg.Follow = false
+	return cfg, nil
+}
+
+// statsParameters parses the stats query parameters, the interval must not split the time window of the query
+// into more than analytics.MaxIntervals intervals
+func statsParameters(query url.Values, cfg logging.ReaderConfig) (interval time.Duration, top int, approx bool, err error) {
+	interval, top = analytics.DefaultInterval, 10
+	if value := query.Get("interval"); value != "" {
+		interval, err = time.ParseDuration(value)
+		if err != nil || interval <= 0 {
+			return 0, 0, false, fmt.Errorf("interval '%s': %w", value, errInvalidParameter)
+		}
+	}
+	err = analytics.CheckInterval(cfg, interval)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	if value := query.Get("top"); value != "" {
+		top, err = strconv.Atoi(value)
+		if err != nil {
+			return 0, 0, false, fmt.Errorf("top '%s': %w", value, errInvalidParameter)
+		}
+	}
+	if value := query.Get("approx"); value != "" {
+		approx, err = strconv.ParseBool(value)
+		if err != nil {
+			return 0, 0, false, fmt.Errorf("approx '%s': %w", value, errInvalidParameter)
+		}
+	}
+	return interval, top, approx, nil
+}
+
+func (s *Server) logf(format string, v ...interface{}) {
+	if s.cfg.Logger != nil {
+		s.cfg.Logger.Printf(format, v...)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// flushWriter flushes every write, so the logs are streamed to the client as they are read,
+// and fails as soon as the request context is done, so the reading stops once the client goes away
+type flushWriter struct {
+	ctx context.Context
+	w   http.ResponseWriter
+}
+
+func (f *flushWriter) Write(p []byte) (int, error) {
+	if err := f.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := f.w.Write(p)
+	if flusher, ok := f.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return n, err
+}
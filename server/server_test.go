@@ -0,0 +1,228 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/steevehook/weblog-analytics/analytics"
+	"github.com/steevehook/weblog-analytics/logging"
+)
+
+const testLogs = `10.0.0.1 - frank [03/Mar/2022:02:41:40 +0000] "GET /api/users HTTP/1.0" 200 100
+10.0.0.2 - frank [03/Mar/2022:02:41:50 +0000] "POST /api/users HTTP/1.0" 500 200
+10.0.0.1 - frank [03/Mar/2022:02:42:00 +0000] "GET /login HTTP/1.0" 302 0
+`
+
+type serverSuite struct {
+	suite.Suite
+	server *Server
+}
+
+func (s *serverSuite) SetupTest() {
+	dir := s.T().TempDir()
+	s.Require().NoError(os.WriteFile(path.Join(dir, "http.log"), []byte(testLogs), 0644))
+	s.server = New(Config{
+		Reader: logging.ReaderConfig{
+			Directory: dir,
+		},
+	})
+}
+
+func (s *serverSuite) Test_Logs_Success() {
+	tests := []struct {
+		name                string
+		query               url.Values
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			name:                "Raw",
+			query:               url.Values{"from": {"2022-03-03T02:41:45Z"}, "format": {"raw"}},
+			expectedContentType: "text/plain; charset=utf-8",
+			expectedBody: `10.0.0.2 - frank [03/Mar/2022:02:41:50 +0000] "POST /api/users HTTP/1.0" 500 200
+10.0.0.1 - frank [03/Mar/2022:02:42:00 +0000] "GET /login HTTP/1.0" 302 0
+`,
+		},
+		{
+			name:                "NDJSON Filter",
+			query:               url.Values{"from": {"2022-03-03T02:00:00Z"}, "filter": {"status >= 500"}},
+			expectedContentType: "application/x-ndjson",
+			expectedBody: `{"remote_host":"10.0.0.2","ident":"-","user":"frank","time":"2022-03-03T02:41:50Z","method":"POST","path":"/api/users","protocol":"HTTP/1.0","status":500,"bytes":200,"referer":"","user_agent":"","duration":0}
+`,
+		},
+		{
+			name:                "Template Time Window",
+			query:               url.Values{"from": {"2022-03-03T02:41:40Z"}, "to": {"2022-03-03T02:41:50Z"}, "template": {"{{.Status}} {{.Path}}"}},
+			expectedContentType: "text/plain; charset=utf-8",
+			expectedBody:        "200 /api/users\n500 /api/users\n",
+		},
+		{
+			name:                "CSV Nothing Found",
+			query:               url.Values{"from": {"2022-03-03T03:00:00Z"}, "format": {"csv"}},
+			expectedContentType: "text/csv; charset=utf-8",
+			expectedBody:        "",
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			req := httptest.NewRequest(http.MethodGet, "/logs?"+test.query.Encode(), nil)
+			rec := httptest.NewRecorder()
+
+			s.server.ServeHTTP(rec, req)
+
+			s.Equal(http.StatusOK, rec.Code)
+			s.Equal(test.expectedContentType, rec.Header().Get("Content-Type"))
+			s.Equal(test.expectedBody, rec.Body.String())
+		})
+	}
+}
+
+func (s *serverSuite) Test_Logs_Error() {
+	tests := []struct {
+		name           string
+		method         string
+		query          url.Values
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Invalid Since",
+			method:         http.MethodGet,
+			query:          url.Values{"since": {"yesterday"}},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"since 'yesterday': invalid parameter"}`,
+		},
+		{
+			name:           "Invalid From",
+			method:         http.MethodGet,
+			query:          url.Values{"from": {"03/Mar/2022"}},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"from '03/Mar/2022': invalid parameter"}`,
+		},
+		{
+			name:           "Invalid Filter",
+			method:         http.MethodGet,
+			query:          url.Values{"filter": {"status >="}},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"filter 'status >=': position 9: unexpected end of filter: invalid filter"}`,
+		},
+		{
+			name:           "Invalid Format",
+			method:         http.MethodGet,
+			query:          url.Values{"format": {"xml"}},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"format 'xml': invalid parameter"}`,
+		},
+		{
+			name:           "Invalid Time Window",
+			method:         http.MethodGet,
+			query:          url.Values{"from": {"2022-03-03T03:00:00Z"}, "to": {"2022-03-03T02:00:00Z"}},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"from 2022-03-03 03:00:00 +0000 UTC is after to 2022-03-03 02:00:00 +0000 UTC: invalid time window"}`,
+		},
+		{
+			name:           "Method Not Allowed",
+			method:         http.MethodPost,
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedBody:   `{"error":"method POST is not allowed"}`,
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			req := httptest.NewRequest(test.method, "/logs?"+test.query.Encode(), nil)
+			rec := httptest.NewRecorder()
+
+			s.server.ServeHTTP(rec, req)
+
+			s.Equal(test.expectedStatus, rec.Code)
+			s.JSONEq(test.expectedBody, rec.Body.String())
+		})
+	}
+}
+
+func (s *serverSuite) Test_Logs_Canceled() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, "/logs?from=2022-03-03T02:00:00Z", nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	s.server.ServeHTTP(rec, req)
+
+	s.Empty(rec.Body.String())
+}
+
+func (s *serverSuite) Test_Stats_Success() {
+	query := url.Values{"from": {"2022-03-03T02:00:00Z"}, "top": {"1"}, "interval": {"1m"}}
+	req := httptest.NewRequest(http.MethodGet, "/stats?"+query.Encode(), nil)
+	rec := httptest.NewRecorder()
+
+	s.server.ServeHTTP(rec, req)
+
+	s.Require().Equal(http.StatusOK, rec.Code)
+	s.Equal("application/json", rec.Header().Get("Content-Type"))
+	var report analytics.Report
+	s.Require().NoError(json.Unmarshal(rec.Body.Bytes(), &report))
+	s.Equal(int64(3), report.Requests)
+	s.Equal(int64(300), report.Bytes)
+	s.Equal([]analytics.Count{{Value: "/api/users", Count: 2}}, report.TopPaths)
+	s.Equal([]analytics.Count{{Value: "10.0.0.1", Count: 2}}, report.TopIPs)
+	s.Len(report.PerInterval, 2)
+	s.False(report.Approximate)
+}
+
+func (s *serverSuite) Test_Stats_Error() {
+	tests := []struct {
+		name         string
+		query        url.Values
+		expectedBody string
+	}{
+		{
+			name:         "Invalid Interval",
+			query:        url.Values{"interval": {"-1m"}},
+			expectedBody: `{"error":"interval '-1m': invalid parameter"}`,
+		},
+		{
+			name:         "Too Many Intervals",
+			query:        url.Values{"since": {"24h"}, "interval": {"1s"}},
+			expectedBody: `{"error":"interval 1s over 24h0m0s: more than 10000 intervals: too many intervals"}`,
+		},
+		{
+			name:         "Too Many Intervals Since Minutes",
+			query:        url.Values{"since": {"1440"}, "interval": {"1s"}},
+			expectedBody: `{"error":"interval 1s over 24h0m0s: more than 10000 intervals: too many intervals"}`,
+		},
+		{
+			name:         "Invalid Top",
+			query:        url.Values{"top": {"ten"}},
+			expectedBody: `{"error":"top 'ten': invalid parameter"}`,
+		},
+		{
+			name:         "Invalid Approx",
+			query:        url.Values{"approx": {"maybe"}},
+			expectedBody: `{"error":"approx 'maybe': invalid parameter"}`,
+		},
+	}
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			req := httptest.NewRequest(http.MethodGet, "/stats?"+test.query.Encode(), nil)
+			rec := httptest.NewRecorder()
+
+			s.server.ServeHTTP(rec, req)
+
+			s.Equal(http.StatusBadRequest, rec.Code)
+			s.JSONEq(test.expectedBody, rec.Body.String())
+		})
+	}
+}
+
+func TestServer(t *testing.T) {
+	suite.Run(t, new(serverSuite))
+}